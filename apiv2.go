@@ -0,0 +1,436 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	proc "github.com/abulimov/cadvisor-companion/process"
+)
+
+var v2ProcessesPath = regexp.MustCompile("^/api/v2/containers/(.+)/processes$")
+var v2ProcessPath = regexp.MustCompile("^/api/v2/containers/(.+)/processes/([0-9]+)$")
+var v2ContainerPidPath = regexp.MustCompile("^/api/v2/containers/(.+)/processes/by-container-pid/([0-9]+)$")
+var v2EnvironPath = regexp.MustCompile("^/api/v2/containers/(.+)/processes/([0-9]+)/environ$")
+var v2AggregatesPath = regexp.MustCompile("^/api/v2/containers/(.+)/aggregates$")
+var v2ConnectionsPath = regexp.MustCompile("^/api/v2/containers/(.+)/connections$")
+var v2NetworkPath = regexp.MustCompile("^/api/v2/containers/(.+)/network$")
+var v2SecurityPath = regexp.MustCompile("^/api/v2/containers/(.+)/security$")
+var v2UninterruptiblePath = regexp.MustCompile("^/api/v2/containers/(.+)/uninterruptible$")
+var v2ZombiesPath = regexp.MustCompile("^/api/v2/containers/(.+)/zombies$")
+var v2OOMPath = regexp.MustCompile("^/api/v2/containers/(.+)/oom$")
+var v2ByNamePath = regexp.MustCompile("^/api/v2/containers/by-name/([^/]+)(/.+)$")
+var v2PodProcessesPath = regexp.MustCompile("^/api/v2/pods/([^/]+)(?:/([^/]+))?/processes$")
+
+// containerInfo is the container listing item
+type containerInfo struct {
+	Name       string                `json:"name"`
+	Runtime    string                `json:"runtime,omitempty"`
+	ID         string                `json:"id,omitempty"`
+	Processes  int                   `json:"processes"`
+	Docker     *proc.DockerContainer `json:"docker,omitempty"`
+	Kubernetes *proc.KubePod         `json:"kubernetes,omitempty"`
+	Pressure   *proc.PressureStats   `json:"pressure,omitempty"`
+}
+
+// apiV2Handler handles http requests to API v2
+func apiV2Handler(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/api/v2/containers" {
+		containersHandler(res)
+		return
+	}
+	if req.URL.Path == "/api/v2/oom" {
+		oomHandler(res, "", false)
+		return
+	}
+	// replace container name with its cgroup and handle request as usual
+	if m := v2ByNamePath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID, err := findContainerByName(m[1])
+		if err != nil {
+			http.Error(res, err.Error(), 404)
+			return
+		}
+		req.URL.Path = "/api/v2/containers" + containerID + m[2]
+	}
+	if req.URL.Path == "/api/v2/namespaces" {
+		namespacesHandler(res)
+		return
+	}
+	if m := v2ContainerPidPath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerPid, _ := strconv.ParseUint(m[2], 10, 64)
+		containerID := "/" + m[1]
+		h := getHistory(req, containerID, len(history))
+		pid, err := findHostPid(h, containerID, containerPid)
+		if err != nil {
+			http.Error(res, err.Error(), 404)
+			return
+		}
+		processHandler(res, h, containerID, pid)
+		return
+	}
+	if m := v2EnvironPath.FindStringSubmatch(req.URL.Path); m != nil {
+		pid, _ := strconv.ParseUint(m[2], 10, 64)
+		containerID := "/" + m[1]
+		environHandler(res, getHistory(req, containerID, 1), containerID, pid)
+		return
+	}
+	if m := v2ProcessPath.FindStringSubmatch(req.URL.Path); m != nil {
+		pid, _ := strconv.ParseUint(m[2], 10, 64)
+		containerID := "/" + m[1]
+		processHandler(res, getHistory(req, containerID, len(history)), containerID, pid)
+		return
+	}
+	if m := v2ProcessesPath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID := "/" + m[1]
+		processesHandler(res, req, getHistory(req, containerID, queryDepth(req)), containerID)
+		return
+	}
+	if m := v2PodProcessesPath.FindStringSubmatch(req.URL.Path); m != nil {
+		podProcessesHandler(res, req, m[1], m[2])
+		return
+	}
+	if m := v2AggregatesPath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID := "/" + m[1]
+		aggregatesHandler(res, getHistory(req, containerID, len(history)), containerID)
+		return
+	}
+	if m := v2ConnectionsPath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID := "/" + m[1]
+		connectionsHandler(res, getHistory(req, containerID, 1), containerID)
+		return
+	}
+	if m := v2NetworkPath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID := "/" + m[1]
+		networkHandler(res, getHistory(req, containerID, len(history)), containerID)
+		return
+	}
+	if m := v2SecurityPath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID := "/" + m[1]
+		securityHandler(res, getHistory(req, containerID, 1), containerID)
+		return
+	}
+	if m := v2UninterruptiblePath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID := "/" + m[1]
+		uninterruptibleHandler(res, getHistory(req, containerID, len(history)), containerID)
+		return
+	}
+	if m := v2ZombiesPath.FindStringSubmatch(req.URL.Path); m != nil {
+		containerID := "/" + m[1]
+		zombiesHandler(res, getHistory(req, containerID, 1), containerID)
+		return
+	}
+	if m := v2OOMPath.FindStringSubmatch(req.URL.Path); m != nil {
+		oomHandler(res, "/"+m[1], req.URL.Query().Get("recursive") == "true")
+		return
+	}
+	http.NotFound(res, req)
+}
+
+// writeJSONResponse writes any result as JSON, or error with HTTP 500
+func writeJSONResponse(res http.ResponseWriter, result interface{}, err error) {
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		res.WriteHeader(500) // HTTP 500
+		io.WriteString(res, err.Error())
+		return
+	}
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		res.WriteHeader(500) // HTTP 500
+		io.WriteString(res, err.Error())
+		return
+	}
+	res.Header().Set("Content-Type", contentTypes[formatJSON])
+	res.Write(jsonResult)
+}
+
+// processHandler returns single process details and its
+// resource usage across all history
+func processHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string, pid uint64) {
+	historyLock.RLock()
+	result, err := h.GetProcessHistory(containerID, pid)
+	historyLock.RUnlock()
+	if err != nil {
+		http.Error(res, err.Error(), 404)
+		return
+	}
+	// collector only counts fds, breakdown by type is read on demand
+	result.Process.ReadFDs(rootPath)
+	writeJSONResponse(res, result, nil)
+}
+
+// splitPatterns splits comma-separated list of patterns
+func splitPatterns(s string) []string {
+	var result []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// environHandler returns redacted environment of container process,
+// only if environment inspection is enabled with -environ flag
+func environHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string, pid uint64) {
+	if !*argEnviron {
+		http.Error(res, "Environment inspection is disabled, enable it with -environ flag", 403)
+		return
+	}
+	// we only show environment of processes of requested container
+	historyLock.RLock()
+	p := (*h)[len(*h)-1][containerID].Processes.FindProc(pid)
+	historyLock.RUnlock()
+	if p == nil {
+		http.Error(res, fmt.Sprintf("Process %d not found in container %s", pid, containerID), 404)
+		return
+	}
+	vars, err := proc.ReadEnviron(filepath.Join(rootPath, "/proc", strconv.FormatUint(pid, 10), "environ"))
+	if err != nil {
+		writeJSONResponse(res, nil, err)
+		return
+	}
+	redactor := proc.Redactor{
+		Patterns: splitPatterns(*argEnvironRedact),
+		Allow:    splitPatterns(*argEnvironAllow),
+	}
+	writeJSONResponse(res, redactor.Redact(vars), nil)
+}
+
+// findHostPid translates PID as it is seen inside the container
+// to host PID using the latest history entry
+func findHostPid(h *proc.HistoryDB, containerID string, containerPid uint64) (uint64, error) {
+	historyLock.RLock()
+	defer historyLock.RUnlock()
+	snap := (*h)[len(*h)-1][containerID]
+	if p := snap.Processes.FindContainerPid(containerPid); p != nil {
+		return p.Status.Pid, nil
+	}
+	return 0, fmt.Errorf("Process with container PID %d not found in container %s", containerPid, containerID)
+}
+
+// namespacesHandler returns groups of containers sharing
+// pid, net or ipc namespaces
+func namespacesHandler(res http.ResponseWriter) {
+	historyLock.RLock()
+	result := history[len(history)-1].SharedNamespaces(proc.SharedNamespaceTypes)
+	historyLock.RUnlock()
+	writeJSONResponse(res, result, nil)
+}
+
+// aggregatesHandler returns time series of container totals
+func aggregatesHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string) {
+	historyLock.RLock()
+	result, err := h.GetAggregates(containerID)
+	historyLock.RUnlock()
+	writeJSONResponse(res, result, err)
+}
+
+// connectionsHandler returns sockets of container's network namespace,
+// read on demand for processes from the latest history entry
+func connectionsHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string) {
+	historyLock.RLock()
+	snap, ok := (*h)[len(*h)-1][containerID]
+	historyLock.RUnlock()
+	if !ok {
+		http.Error(res, fmt.Sprintf("Container %s not found", containerID), 404)
+		return
+	}
+	result, err := proc.GetConnections(rootPath, snap.Processes)
+	writeJSONResponse(res, result, err)
+}
+
+// securityHandler returns security posture report of processes
+// from the latest history entry
+func securityHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string) {
+	historyLock.RLock()
+	snap, ok := (*h)[len(*h)-1][containerID]
+	historyLock.RUnlock()
+	if !ok {
+		http.Error(res, fmt.Sprintf("Container %s not found", containerID), 404)
+		return
+	}
+	writeJSONResponse(res, proc.GetSecurityReport(rootPath, snap.Processes), nil)
+}
+
+// uninterruptibleHandler returns container processes in D state
+// with kernel functions they are blocked in
+func uninterruptibleHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string) {
+	historyLock.RLock()
+	result, err := h.GetUninterruptible(containerID)
+	historyLock.RUnlock()
+	for i := range result {
+		result[i].ReadKernelInfo(rootPath)
+	}
+	writeJSONResponse(res, result, err)
+}
+
+// zombiesHandler returns zombies of container attributed
+// to their parents, from the latest history entry
+func zombiesHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string) {
+	historyLock.RLock()
+	snap, ok := (*h)[len(*h)-1][containerID]
+	historyLock.RUnlock()
+	if !ok {
+		http.Error(res, fmt.Sprintf("Container %s not found", containerID), 404)
+		return
+	}
+	writeJSONResponse(res, snap.Processes.GetZombieReport(), nil)
+}
+
+// networkHandler returns time series of container network interfaces
+func networkHandler(res http.ResponseWriter, h *proc.HistoryDB, containerID string) {
+	historyLock.RLock()
+	result, err := h.GetNetwork(containerID)
+	historyLock.RUnlock()
+	writeJSONResponse(res, result, err)
+}
+
+// metadataCache keeps Docker containers metadata by container ID and
+// Kubernetes pods metadata by pod UID, so listings don't re-read runtime
+// files on every request. Entries of containers which are gone are dropped
+type metadataCache struct {
+	sync.Mutex
+	docker map[string]*proc.DockerContainer
+	pods   map[string]proc.KubePod
+}
+
+// containersMeta is shared by all api handlers
+var containersMeta metadataCache
+
+// Resolve sets Docker and Kubernetes metadata of containers, reading
+// files only for containers not seen before. Unreadable metadata is not
+// cached, as container may be still starting
+func (cache *metadataCache) Resolve(containers []containerInfo) {
+	cache.Lock()
+	defer cache.Unlock()
+	docker := make(map[string]*proc.DockerContainer)
+	pods := make(map[string]proc.KubePod)
+	for i := range containers {
+		c := &containers[i]
+		if id, ok := proc.DockerID(c.Name); ok {
+			d, ok := cache.docker[id]
+			if !ok {
+				d, _ = proc.ReadDockerContainer(rootPath, id)
+			}
+			if d != nil {
+				docker[id] = d
+			}
+			c.Docker = d
+		}
+		if pod, ok := proc.ParseKubeCgroup(c.Name); ok {
+			cached, ok := pods[pod.PodUID]
+			if !ok {
+				cached, ok = cache.pods[pod.PodUID]
+			}
+			if ok {
+				pod.Namespace, pod.Name = cached.Namespace, cached.Name
+			} else {
+				pod.Resolve(rootPath)
+			}
+			if pod.Namespace != "" && pod.Name != "" {
+				pods[pod.PodUID] = *pod
+			}
+			c.Kubernetes = pod
+		}
+	}
+	cache.docker = docker
+	cache.pods = pods
+}
+
+// getContainers returns listing of all containers in the latest
+// history entry, with Docker metadata when available
+func getContainers() []containerInfo {
+	historyLock.RLock()
+	result := make([]containerInfo, 0)
+	for _, name := range history.Containers() {
+		snap := history[len(history)-1][name]
+		c := containerInfo{
+			Name:      name,
+			Processes: len(snap.Processes),
+			Pressure:  snap.Pressure,
+		}
+		if ref, ok := proc.NormalizeCgroup(name); ok {
+			c.Runtime = ref.Runtime
+			c.ID = ref.ID
+		}
+		result = append(result, c)
+	}
+	historyLock.RUnlock()
+	// metadata files are read without lock not to stall the collector
+	containersMeta.Resolve(result)
+	return result
+}
+
+// findContainerByName returns cgroup of container with given Docker name
+func findContainerByName(name string) (string, error) {
+	for _, c := range getContainers() {
+		if c.Docker != nil && c.Docker.Name == name {
+			return c.Name, nil
+		}
+	}
+	return "", fmt.Errorf("Container with name %s not found", name)
+}
+
+// containersHandler returns listing of all known containers
+func containersHandler(res http.ResponseWriter) {
+	writeJSONResponse(res, getContainers(), nil)
+}
+
+// findPodUID returns UID of pod with given namespace and name
+func findPodUID(namespace, name string) (string, error) {
+	for _, c := range getContainers() {
+		pod := c.Kubernetes
+		if pod != nil && pod.Namespace == namespace && pod.Name == name {
+			return pod.PodUID, nil
+		}
+	}
+	return "", fmt.Errorf("Pod %s/%s not found", namespace, name)
+}
+
+// podProcessesHandler returns processes of all pod containers, pod is
+// requested either by UID or by namespace and name
+func podProcessesHandler(res http.ResponseWriter, req *http.Request, first, second string) {
+	uid := first
+	if second != "" {
+		var err error
+		if uid, err = findPodUID(first, second); err != nil {
+			http.Error(res, err.Error(), 404)
+			return
+		}
+	}
+	historyLock.RLock()
+	podHistory := history.Merged(uid, queryDepth(req), func(containerID string) bool {
+		pod, ok := proc.ParseKubeCgroup(containerID)
+		return ok && pod.PodUID == uid
+	})
+	historyLock.RUnlock()
+	processesHandler(res, req, podHistory, uid)
+}
+
+// oomHandler returns detected OOM kills, for all containers
+// if containerID is empty, for container subtree if recursive
+func oomHandler(res http.ResponseWriter, containerID string, recursive bool) {
+	historyLock.RLock()
+	defer historyLock.RUnlock()
+	if containerID == "" {
+		writeJSONResponse(res, append(make(proc.OOMLog, 0), oomLog...), nil)
+		return
+	}
+	if recursive {
+		writeJSONResponse(res, oomLog.ForSubtree(containerID), nil)
+		return
+	}
+	writeJSONResponse(res, oomLog.ForContainer(containerID), nil)
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	proc "github.com/abulimov/cadvisor-companion/process"
+)
+
+func TestAPIV2HandlerWrongUrl(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://localhost:8801/api/v2/strange/url", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 404
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+}
+
+func TestAPIV2HandlerProcesses(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v2/containers%s/processes?sort=cpu", containerName)
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+}
+
+func TestAPIV2HandlerProcess(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v2/containers%s/processes/6930", containerName)
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result proc.ProcessHistory
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	expectedCmdline := "/usr/bin/cadvisor-companion"
+	if result.Process.Cmdline != expectedCmdline {
+		t.Errorf("%s not equal to expected %s", result.Process.Cmdline, expectedCmdline)
+	}
+	if len(result.Samples) < 2 {
+		t.Errorf("%d samples, expected at least 2", len(result.Samples))
+	}
+	if result.Process.FDs == nil || result.Process.FDs.Pipes != 2 || result.Process.FDs.Sockets != 1 {
+		t.Errorf("%+v doesn't have fds breakdown by type", result.Process.FDs)
+	}
+}
+
+func TestAPIV2HandlerWrongProcess(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v2/containers%s/processes/1", containerName)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 404
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+}
+
+func TestAPIV2HandlerAggregates(t *testing.T) {
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	u := fmt.Sprintf("http://localhost:8801/api/v2/containers%s/aggregates", containerName)
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result []proc.Aggregate
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	expectedProcesses := 3
+	if len(result) == 0 || result[len(result)-1].Processes != expectedProcesses {
+		t.Errorf("%v last aggregate doesn't have expected %d processes", result, expectedProcesses)
+	}
+}
+
+func TestAPIV2HandlerContainers(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", "http://localhost:8801/api/v2/containers", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result []containerInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	found := false
+	for _, c := range result {
+		if c.Docker != nil && c.Docker.Name == "munin-node" {
+			found = true
+			expectedRuntime := "docker"
+			if c.Runtime != expectedRuntime {
+				t.Errorf("%s not equal to expected %s", c.Runtime, expectedRuntime)
+			}
+			expectedProcesses := 3
+			if c.Processes != expectedProcesses {
+				t.Errorf("%d not equal to expected %d", c.Processes, expectedProcesses)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("%v does not contain munin-node container", result)
+	}
+}
+
+func TestAPIV2HandlerByName(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	urls := map[string]int{
+		"http://localhost:8801/api/v2/containers/by-name/munin-node/processes":      200,
+		"http://localhost:8801/api/v2/containers/by-name/munin-node/processes/8713": 200,
+		"http://localhost:8801/api/v2/containers/by-name/munin-node/aggregates":     200,
+		"http://localhost:8801/api/v2/containers/by-name/missing/processes":         404,
+	}
+	for u, expectedCode := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		if expectedCode != w.Code {
+			t.Errorf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+	}
+}
+
+// pushKubeHistory pushes history entries with testroot processes
+// placed into Kubernetes pod containers
+func pushKubeHistory() error {
+	procs, err := proc.GetProcesses("process/testroot/")
+	if err != nil {
+		return err
+	}
+	podCgroup := "/kubepods/burstable/pod0b1f2c9e-4d6a-4c1b-9a8e-2f3d4c5b6a71/"
+	entry := make(proc.HistoryEntry)
+	for i, p := range procs[:2] {
+		cgroup := fmt.Sprintf("%s%064d", podCgroup, i)
+		p.Cgroup = cgroup
+		entry[cgroup] = proc.Snapshot{Timestamp: time.Now(), Processes: proc.List{p}}
+	}
+	historyLock.Lock()
+	history.Push(entry)
+	history.Push(entry)
+	historyLock.Unlock()
+	return nil
+}
+
+func TestAPIV2HandlerPods(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	if err := pushKubeHistory(); err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	urls := map[string]int{
+		"http://localhost:8801/api/v2/pods/0b1f2c9e-4d6a-4c1b-9a8e-2f3d4c5b6a71/processes": 200,
+		"http://localhost:8801/api/v2/pods/production/web-7f9c6d8b5-2mxkq/processes":       200,
+		"http://localhost:8801/api/v2/pods/production/missing/processes":                   404,
+	}
+	for u, expectedCode := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		if expectedCode != w.Code {
+			t.Errorf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+			continue
+		}
+		if expectedCode != 200 {
+			continue
+		}
+		var result []proc.Snapshot
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		expectedLen := 2
+		if len(result) != 1 || len(result[0].Processes) != expectedLen {
+			t.Errorf("%v does not contain expected %d pod processes", result, expectedLen)
+		}
+	}
+}
+
+func TestAPIV2HandlerOOM(t *testing.T) {
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	historyLock.Lock()
+	oomLog.Push(proc.OOMEvent{Timestamp: time.Now(), Container: containerName, Kills: 1})
+	historyLock.Unlock()
+	urls := map[string]int{
+		"http://localhost:8801/api/v2/oom":                                  1,
+		"http://localhost:8801/api/v2/containers" + containerName + "/oom":  1,
+		"http://localhost:8801/api/v2/containers/docker/missing/oom":        0,
+		"http://localhost:8801/api/v2/containers/docker/oom":                0,
+		"http://localhost:8801/api/v2/containers/docker/oom?recursive=true": 1,
+	}
+	for u, expectedLen := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		expectedCode := 200
+		if expectedCode != w.Code {
+			t.Fatalf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+		var result []proc.OOMEvent
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		if len(result) < expectedLen || (expectedLen == 0 && len(result) != 0) {
+			t.Errorf("%d events, expected %d for url %v", len(result), expectedLen, u)
+		}
+	}
+}
+
+func TestAPIV2HandlerConnections(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	urls := map[string]int{
+		"http://localhost:8801/api/v2/containers/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a/connections": 200,
+		"http://localhost:8801/api/v2/containers/by-name/munin-node/connections":                                                      200,
+		"http://localhost:8801/api/v2/containers/docker/missing/connections":                                                          404,
+	}
+	for u, expectedCode := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		if expectedCode != w.Code {
+			t.Fatalf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+		if expectedCode != 200 {
+			continue
+		}
+		var result proc.Connections
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		if len(result.Listening) == 0 {
+			t.Errorf("%v has no listening sockets for url %v", result, u)
+		}
+	}
+}
+
+func TestAPIV2HandlerNetwork(t *testing.T) {
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	u := fmt.Sprintf("http://localhost:8801/api/v2/containers%s/network", containerName)
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result []proc.NetworkSample
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	if len(result) < 2 {
+		t.Fatalf("%d samples, expected at least %d", len(result), 2)
+	}
+	last := result[len(result)-1]
+	if last.NetworkStats == nil || len(last.Interfaces) != 2 || len(last.Shared) != 1 {
+		t.Errorf("%v is not expected network sample", last)
+	}
+}
+
+func TestAPIV2HandlerContainerPid(t *testing.T) {
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	urls := map[string]int{
+		"http://localhost:8801/api/v2/containers/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a/processes/by-container-pid/12":  200,
+		"http://localhost:8801/api/v2/containers/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a/processes/by-container-pid/100": 404,
+	}
+	for u, expectedCode := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		if expectedCode != w.Code {
+			t.Fatalf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+		if expectedCode != 200 {
+			continue
+		}
+		var result proc.ProcessHistory
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		var expectedPid uint64 = 8743
+		if result.Process.Status.Pid != expectedPid {
+			t.Errorf("%v not equal to expected %v", result.Process.Status.Pid, expectedPid)
+		}
+	}
+}
+
+func TestAPIV2HandlerNamespaces(t *testing.T) {
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", "http://localhost:8801/api/v2/namespaces", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result []proc.NamespaceGroup
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	expectedLen := 2
+	if len(result) != expectedLen {
+		t.Errorf("%d not equal to expected %d", len(result), expectedLen)
+	}
+}
+
+func TestAPIV2HandlerSecurity(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	urls := map[string]int{
+		"http://localhost:8801/api/v2/containers/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a/security": 200,
+		"http://localhost:8801/api/v2/containers/docker/missing/security":                                                          404,
+	}
+	for u, expectedCode := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		if expectedCode != w.Code {
+			t.Fatalf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+		if expectedCode != 200 {
+			continue
+		}
+		var result proc.SecurityReport
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		expectedHighlighted := 2
+		if result.Highlighted != expectedHighlighted {
+			t.Errorf("%d not equal to expected %d", result.Highlighted, expectedHighlighted)
+		}
+	}
+}
+
+func TestAPIV2HandlerEnviron(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	u := "http://localhost:8801/api/v2/containers" + containerName + "/processes/8743/environ"
+	urls := map[string]int{
+		u: 200,
+		"http://localhost:8801/api/v2/containers" + containerName + "/processes/6930/environ": 404,
+	}
+	check := func(u string, expectedCode int) {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		if expectedCode != w.Code {
+			t.Fatalf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+		if expectedCode != 200 {
+			return
+		}
+		var result []proc.EnvVar
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		// only variables allowed by default are shown in clear
+		expected := map[string]bool{"PATH": true, "HOSTNAME": true, "HOME": true}
+		for _, v := range result {
+			if v.Redacted == expected[v.Key] {
+				t.Errorf("%v redacted flag is wrong", v)
+			}
+		}
+	}
+
+	// disabled by default
+	check(u, 403)
+
+	*argEnviron = true
+	defer func() { *argEnviron = false }()
+	for u, expectedCode := range urls {
+		check(u, expectedCode)
+	}
+}
+
+func TestAPIV2HandlerUninterruptible(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	containerName := "/docker/ac4cd7be58276b4f801a86b52b83319c7e7b08e4e4eed15bbe1122355325e3e1"
+	u := "http://localhost:8801/api/v2/containers" + containerName + "/uninterruptible"
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiV2Handler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result []proc.BlockedProcess
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	if len(result) != 1 || !result[0].Stuck || result[0].Wchan != "io_schedule" {
+		t.Errorf("%v is not expected list of blocked processes", result)
+	}
+}
+
+func TestAPIV2HandlerZombies(t *testing.T) {
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	urls := map[string]int{
+		"http://localhost:8801/api/v2/containers/docker/ac4cd7be58276b4f801a86b52b83319c7e7b08e4e4eed15bbe1122355325e3e1/zombies": 200,
+		"http://localhost:8801/api/v2/containers/docker/missing/zombies":                                                          404,
+	}
+	for u, expectedCode := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiV2Handler(w, req)
+		if expectedCode != w.Code {
+			t.Fatalf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+		if expectedCode != 200 {
+			continue
+		}
+		var result proc.ZombieReport
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		if result.Count != 1 || len(result.Parents) != 1 || result.Parents[0].Pid != 8347 {
+			t.Errorf("%v is not expected zombie report", result)
+		}
+	}
+}
+
+func TestMetadataCacheResolve(t *testing.T) {
+	rootPath = "process/testroot/"
+	defer func() { rootPath = "/" }()
+	var cache metadataCache
+	name := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	containers := []containerInfo{{Name: name}}
+	cache.Resolve(containers)
+	if containers[0].Docker == nil || containers[0].Docker.Name != "munin-node" {
+		t.Fatalf("%v is not expected Docker metadata", containers[0].Docker)
+	}
+	if len(cache.docker) != 1 {
+		t.Errorf("%d cached containers, expected 1", len(cache.docker))
+	}
+	// cached metadata is used even if files are gone
+	rootPath = "/nonexistent"
+	containers = []containerInfo{{Name: name}}
+	cache.Resolve(containers)
+	if containers[0].Docker == nil {
+		t.Error("cached Docker metadata was not used")
+	}
+	cache.Resolve(nil)
+	if len(cache.docker) != 0 {
+		t.Errorf("%d cached containers, expected 0", len(cache.docker))
+	}
+}
@@ -5,7 +5,6 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -13,6 +12,9 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	proc "github.com/abulimov/cadvisor-companion/process"
@@ -24,55 +26,240 @@ var version = "0.1.4"
 var argIP = flag.String("listen_ip", "", "IP to listen on, defaults to all IPs")
 var argPort = flag.Int("port", 8801, "port to listen")
 var versionFlag = flag.Bool("version", false, "print cAdvisor-companion version and exit")
+var argHostProcesses = flag.Bool("host_processes", false, "also collect host processes under "+proc.HostContainer+" pseudo-container")
+var argEnviron = flag.Bool("environ", false, "enable processes environment inspection API")
+var argEnvironRedact = flag.String("environ_redact", strings.Join(proc.DefaultRedactPatterns, ","), "comma-separated patterns of environment variables to redact even if they match -environ_allow")
+var argEnvironAllow = flag.String("environ_allow", strings.Join(proc.DefaultAllowPatterns, ","), "comma-separated patterns of environment variables to show in clear, all others are redacted")
 
 var history proc.HistoryDB
 
-// apiHandler handles http requests
-func apiHandler(res http.ResponseWriter, req *http.Request) {
-	var validPath = regexp.MustCompile("^/api/v1.0/(.+)/processes$")
+// rootPath is where our /proc is mounted.
+// When we are inside the container, host's /proc should be mounted
+// at /rootfs/proc, so rootPath is /rootfs
+var rootPath = "/"
 
-	// validate requested URL
-	m := validPath.FindStringSubmatch(req.URL.Path)
-	if m == nil {
-		http.NotFound(res, req)
-		return
-	}
+// oomLog keeps OOM kills detected by collector
+var oomLog proc.OOMLog
+
+// namesCache keeps container users and groups, used only by collector
+var namesCache proc.NamesCache
 
-	// process get parameters
+// historyLock guards history and oomLog from concurrent collector and api access
+var historyLock sync.RWMutex
 
-	// sortStr is used to get top sorted procs
-	sortStr := req.URL.Query().Get("sort")
+// updates notifies streaming clients about new history entries
+var updates = newBroker()
 
+// memTotal is the amount of host memory in kB, used for %MEM,
+// accessed atomically as api handlers read it without historyLock
+var memTotal uint64
+
+// query holds parsed get parameters of processes request
+type query struct {
+	// sort is used to get top sorted procs
+	sort string
 	// limit is used to limit top sorted procs
+	limit int
+	// user is used to filter procs by user name or UID
+	user string
+	// suspicious is used to get only procs with suspicious executables
+	suspicious bool
+	// interval is the interval we use to calculate CPU usage
+	// and to iterate back to the past
+	interval int
+	// count is the count of resulting points in time
+	count int
+	// since and until limit time range of resulting points in time
+	since time.Time
+	until time.Time
+}
+
+// isRange tells if query asks for time range instead of `count` entries
+func (q query) isRange() bool {
+	return !q.since.IsZero() || !q.until.IsZero()
+}
+
+// parseQuery processes get parameters
+func parseQuery(req *http.Request) (query, error) {
+	var q query
+	q.sort = req.URL.Query().Get("sort")
+	q.user = req.URL.Query().Get("user")
+	q.suspicious = req.URL.Query().Get("suspicious") == "true"
+
 	limitStr := req.URL.Query().Get("limit")
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit < 0 {
 		limit = 0
 	}
+	q.limit = limit
 
-	// interval is the interval we use to calculate CPU usage
-	// and to iterate back to the past
 	intervalStr := req.URL.Query().Get("interval")
 	interval, err := strconv.Atoi(intervalStr)
 	if err != nil || interval < 1 {
 		interval = 1
 	}
+	q.interval = interval
 
-	// count is the count of resulting points in time
 	countStr := req.URL.Query().Get("count")
 	count, err := strconv.Atoi(countStr)
 	if err != nil || count < 1 {
 		count = 1
 	}
+	q.count = count
+
+	for param, t := range map[string]*time.Time{"since": &q.since, "until": &q.until} {
+		str := req.URL.Query().Get(param)
+		if str == "" {
+			continue
+		}
+		if *t, err = time.Parse(time.RFC3339, str); err != nil {
+			return q, fmt.Errorf("Wrong %s parameter: %s", param, err)
+		}
+	}
+
+	// unknown sort is a client error, so it's checked before any data is read
+	if _, err := sortProcesses(nil, q); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// filterProcesses returns processes matching query filters
+func filterProcesses(procs proc.List, q query) proc.List {
+	if q.suspicious {
+		procs = procs.Suspicious()
+	}
+	if q.user == "" {
+		return procs
+	}
+	var result proc.List
+	for _, p := range procs {
+		if p.User == q.user || strconv.FormatUint(p.Status.RealUid, 10) == q.user {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// sortProcesses sorts and limits processes according to query
+func sortProcesses(procs proc.List, q query) (proc.List, error) {
+	switch q.sort {
+	case "cpu":
+		return procs.TopCPU(q.limit), nil
+	case "mem":
+		return procs.TopMem(q.limit), nil
+	case "fds":
+		return procs.TopFDs(q.limit), nil
+	case "wait":
+		return procs.TopWait(q.limit), nil
+	case "ctxt":
+		return procs.TopCtxtSwitches(q.limit), nil
+	case "minflt":
+		return procs.TopMinflt(q.limit), nil
+	case "majflt":
+		return procs.TopMajflt(q.limit), nil
+	case "":
+		return procs, nil
+	}
+	return nil, fmt.Errorf("Unknown sort %s", q.sort)
+}
+
+// getRange returns sorted and limited snapshots of container's processes
+// in query time range, along with timestamp of the newest history entry
+func getRange(h *proc.HistoryDB, containerID string, q query) ([]proc.Snapshot, time.Time, error) {
+	historyLock.RLock()
+	defer historyLock.RUnlock()
+	newest := h.Newest()
+	result, err := h.GetRange(containerID, q.since, q.until, q.interval)
+	if err != nil {
+		return nil, newest, err
+	}
+	for i := range result {
+		if result[i].Processes, err = sortProcesses(filterProcesses(result[i].Processes, q), q); err != nil {
+			return nil, newest, err
+		}
+	}
+	return result, newest, nil
+}
+
+// getHistory returns history to look for container in, which is
+// container subtree merged under container name for recursive requests.
+// As merging is done per request, only `depth` latest entries are merged
+func getHistory(req *http.Request, containerID string, depth int) *proc.HistoryDB {
+	if req.URL.Query().Get("recursive") != "true" {
+		return &history
+	}
+	historyLock.RLock()
+	defer historyLock.RUnlock()
+	return history.Subtree(containerID, depth)
+}
+
+// queryDepth returns count of latest history entries processes query
+// needs, which is all of them for time range queries
+func queryDepth(req *http.Request) int {
+	q, err := parseQuery(req)
+	if err != nil || q.isRange() {
+		return len(history)
+	}
+	return q.count*q.interval + 1
+}
+
+// getSnapshot returns sorted and limited snapshot of container's processes
+// `offset` history entries back from now
+func getSnapshot(h *proc.HistoryDB, containerID string, q query, offset int) (*proc.Snapshot, error) {
+	historyLock.RLock()
+	defer historyLock.RUnlock()
+	ps, err := h.GetLastData(containerID, q.interval, offset)
+	if err != nil {
+		return nil, err
+	}
+	if ps.Processes, err = sortProcesses(filterProcesses(ps.Processes, q), q); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// apiHandler handles http requests
+func apiHandler(res http.ResponseWriter, req *http.Request) {
+	var validPath = regexp.MustCompile("^/api/v1.0/(.+)/processes$")
+	var streamPath = regexp.MustCompile("^/api/v1.0/(.+)/processes/stream$")
+
+	if m := streamPath.FindStringSubmatch(req.URL.Path); m != nil {
+		streamHandler(res, req, "/"+m[1])
+		return
+	}
+
+	// validate requested URL
+	m := validPath.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		http.NotFound(res, req)
+		return
+	}
 
 	// containerID is the name of our container/cgroup
 	containerID := "/" + m[1]
+	processesHandler(res, req, getHistory(req, containerID, queryDepth(req)), containerID)
+}
+
+// processesHandler returns container's processes from history `h`
+// for the last `count` intervals or for the requested time range
+func processesHandler(res http.ResponseWriter, req *http.Request, h *proc.HistoryDB, containerID string) {
+	q, err := parseQuery(req)
+	if err != nil {
+		res.WriteHeader(400) // HTTP 400
+		io.WriteString(res, err.Error())
+		return
+	}
+
+	// format is the output format, either from get parameter
+	// or from Accept header
+	format, err := getFormat(req)
+	if err != nil {
+		res.WriteHeader(400) // HTTP 400
+		io.WriteString(res, err.Error())
+		return
+	}
 
-	// set response headers
-	res.Header().Set(
-		"Content-Type",
-		"text/json",
-	)
 	var result []proc.Snapshot
 	var ps *proc.Snapshot
 	fail := func(err error) {
@@ -82,25 +269,40 @@ func apiHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// get data for all `count` HistoryEntries
-	for i := count - 1; i >= 0; i-- {
-		// case our possible sort parameters
-		switch sortStr {
-		case "cpu":
-			ps, err = history.GetTopCPU(containerID, limit, interval, i*interval+1)
-		case "mem":
-			ps, err = history.GetTopMem(containerID, limit, interval, i*interval+1)
-		case "":
-			ps, err = history.GetLastData(containerID, interval, i*interval+1)
+	// get data for the requested time range, telling client
+	// where to start the next range query
+	if q.isRange() {
+		var newest time.Time
+		result, newest, err = getRange(h, containerID, q)
+		if err != nil {
+			fail(err)
+			return
+		}
+		cursor := newest.Format(time.RFC3339Nano)
+		res.Header().Set("X-Cursor", cursor)
+		if format == formatJSON {
+			err = writeRangeJSON(res, cursor, result)
+		} else {
+			err = writeResult(res, format, result)
 		}
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+		}
+		return
+	}
+
+	// get data for all `count` HistoryEntries
+	for i := q.count - 1; i >= 0; i-- {
+		ps, err = getSnapshot(h, containerID, q, i*q.interval+1)
 		if err != nil {
 			fail(err)
 			return
 		}
 		result = append(result, *ps)
 	}
-	jsonResult, _ := json.Marshal(result)
-	io.WriteString(res, string(jsonResult))
+	if err := writeResult(res, format, result); err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
 }
 
 // collectData scrapes procs data for all containers
@@ -108,15 +310,52 @@ func apiHandler(res http.ResponseWriter, req *http.Request) {
 func collectData(rootPath string) {
 	timeStamp := time.Now()
 	// get all processes without cgroup grouping
-	allProcs, _ := proc.GetProcesses(rootPath)
+	var allProcs proc.List
+	if *argHostProcesses {
+		allProcs, _ = proc.GetProcessesWithHost(rootPath)
+	} else {
+		allProcs, _ = proc.GetProcesses(rootPath)
+	}
+	namesCache.Resolve(rootPath, allProcs)
 	// group all processes by their cgroups
 	cgroupsProcs := allProcs.GetCgroupsMap()
 
+	// only collector modifies history, so it's safe to read it without lock
+	prevEntry := history[len(history)-1]
+
+	networks := proc.GetNetworks(rootPath, cgroupsProcs)
+
 	entry := make(proc.HistoryEntry)
+	var ooms []proc.OOMEvent
 	for e, p := range cgroupsProcs {
-		entry[e] = proc.Snapshot{Timestamp: timeStamp, Processes: p}
+		snap := proc.Snapshot{Timestamp: timeStamp, Processes: p, Network: networks[e]}
+		prev := prevEntry[e]
+		snap.Network.SetRates(prev.Network, timeStamp.Sub(prev.Timestamp).Seconds())
+		// pseudo-containers have no cgroup to read accounting from
+		if e != proc.HostContainer {
+			snap.Cgroup, _ = proc.ReadCgroupStats(rootPath, e)
+			snap.Pressure, _ = proc.ReadPressure(rootPath, e)
+			snap.Pressure.SetDeltas(prevEntry[e].Pressure)
+			if oom := proc.DetectOOM(e, prevEntry[e], snap); oom != nil {
+				ooms = append(ooms, *oom)
+			}
+		}
+		entry[e] = snap
 	}
+	// containers whose only process was killed are gone from cgroupsProcs
+	ooms = append(ooms, proc.DetectVanishedOOM(rootPath, prevEntry, entry, timeStamp)...)
+	if total, err := proc.GetMemTotal(rootPath); err == nil {
+		atomic.StoreUint64(&memTotal, total)
+	}
+
+	historyLock.Lock()
 	history.Push(entry)
+	for _, oom := range ooms {
+		fmt.Printf("OOM kill in container %s\n", oom.Container)
+		oomLog.Push(oom)
+	}
+	historyLock.Unlock()
+	updates.Notify()
 }
 
 // collector runs collectData every second
@@ -146,10 +385,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	// rootPath is where our /proc is mounted.
-	// When we are inside the container, host's /proc should be mounted
-	// at /rootfs/proc, so rootPath is /rootfs
-	rootPath := getRootPath()
+	rootPath = getRootPath()
 
 	// start collecting data
 	go collector(rootPath)
@@ -157,6 +393,8 @@ func main() {
 	addr := fmt.Sprintf("%s:%d", *argIP, *argPort)
 	fmt.Printf("Starting cAdvisor-companion version: %q on port %d\n", version, *argPort)
 	http.HandleFunc("/api/", apiHandler)
+	http.HandleFunc("/api/v2/", apiV2Handler)
+	http.HandleFunc("/metrics", metricsHandler)
 	err := http.ListenAndServe(addr, nil)
 	if err != nil {
 		fmt.Println(err)
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	proc "github.com/abulimov/cadvisor-companion/process"
 )
 
 func TestAPIHandlerWrongUrl(t *testing.T) {
@@ -59,6 +65,11 @@ func TestAPIHandlerNormal(t *testing.T) {
 		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes", containerName),
 		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=mem", containerName),
 		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=cpu", containerName),
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=fds", containerName),
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=wait", containerName),
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=ctxt", containerName),
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=majflt", containerName),
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=minflt", containerName),
 	}
 	collectData("process/testroot/")
 	collectData("process/testroot/")
@@ -75,3 +86,345 @@ func TestAPIHandlerNormal(t *testing.T) {
 		}
 	}
 }
+
+func TestAPIHandlerFormats(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	urls := map[string]string{
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes", containerName):             "text/json",
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?format=json", containerName): "text/json",
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?format=csv", containerName):  "text/csv",
+		fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?format=text", containerName): "text/plain",
+	}
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	for u, expectedType := range urls {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiHandler(w, req)
+		expectedCode := 200
+		if expectedCode != w.Code {
+			t.Errorf("%v HTTP code not equal to expected %v for url %v", w.Code, expectedCode, u)
+		}
+		gotType := w.Header().Get("Content-Type")
+		if expectedType != gotType {
+			t.Errorf("%v Content-Type not equal to expected %v for url %v", gotType, expectedType, u)
+		}
+	}
+}
+
+func TestAPIHandlerFormatText(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?format=text", containerName)
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	expected := "/usr/bin/cadvisor-companion\n"
+	if !strings.HasSuffix(w.Body.String(), expected) {
+		t.Errorf("%q does not end with expected %q", w.Body.String(), expected)
+	}
+	if !strings.Contains(w.Body.String(), " USER   PID  %CPU  %MEM      VSZ      RSS   STAT COMMAND\n") {
+		t.Errorf("%q does not contain ps-like header", w.Body.String())
+	}
+}
+
+func TestAPIHandlerAcceptHeader(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes", containerName)
+	accepts := map[string]string{
+		"text/csv":                       "text/csv",
+		"text/plain;q=0.9, text/csv":     "text/csv",
+		"text/plain, text/csv":           "text/plain",
+		"application/json":               "text/json",
+		"text/html, */*;q=0.8":           "text/json",
+		"application/xml, text/csv;q=.5": "text/csv",
+	}
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	for accept, expectedType := range accepts {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		apiHandler(w, req)
+		gotType := w.Header().Get("Content-Type")
+		if expectedType != gotType {
+			t.Errorf("%v Content-Type not equal to expected %v for Accept %v", gotType, expectedType, accept)
+		}
+	}
+}
+
+func TestAPIHandlerWrongFormat(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?format=xml", containerName)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	expectedCode := 400
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+}
+
+func TestAPIHandlerWrongSort(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?sort=name", containerName)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	expectedCode := 400
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+}
+
+func TestAPIHandlerStream(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	server := httptest.NewServer(http.HandlerFunc(apiHandler))
+	defer server.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1.0%s/processes/stream?sort=mem&limit=1", server.URL, containerName))
+	if err != nil {
+		t.Fatal("stream request failed", err)
+	}
+	expectedType := "text/event-stream"
+	if gotType := resp.Header.Get("Content-Type"); expectedType != gotType {
+		t.Errorf("%v Content-Type not equal to expected %v", gotType, expectedType)
+	}
+
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal("reading stream failed", err)
+	}
+	if !strings.HasPrefix(line, "data: {") {
+		t.Errorf("%q is not a data event", line)
+	}
+
+	// subscription must be gone after client disconnects
+	resp.Body.Close()
+	for i := 0; updates.Len() != 0; i++ {
+		if i > 100 {
+			t.Fatal("subscription was not cleaned up after disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBrokerNotifyCoalesces(t *testing.T) {
+	b := newBroker()
+	ch := b.Subscribe()
+	// slow subscriber must not block Notify
+	b.Notify()
+	b.Notify()
+	b.Notify()
+	<-ch
+	select {
+	case <-ch:
+		t.Error("notifications were not coalesced")
+	default:
+	}
+	b.Unsubscribe(ch)
+	if b.Len() != 0 {
+		t.Errorf("%d subscribers left, expected 0", b.Len())
+	}
+}
+
+func TestAPIHandlerSince(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	since := time.Now().Add(-time.Second).Format(time.RFC3339)
+	u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?since=%s&sort=cpu", containerName, since)
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result struct {
+		Cursor    string          `json:"cursor"`
+		Snapshots []proc.Snapshot `json:"snapshots"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	if len(result.Snapshots) == 0 {
+		t.Error("no snapshots returned for since query")
+	}
+	if result.Cursor == "" || result.Cursor != w.Header().Get("X-Cursor") {
+		t.Errorf("%q cursor not equal to X-Cursor header %q", result.Cursor, w.Header().Get("X-Cursor"))
+	}
+}
+
+func TestAPIHandlerWrongSince(t *testing.T) {
+	containerName := "/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"
+	u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?since=yesterday", containerName)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	expectedCode := 400
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+}
+
+func TestAPIHandlerHostProcesses(t *testing.T) {
+	*argHostProcesses = true
+	defer func() { *argHostProcesses = false }()
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", "http://localhost:8801/api/v1.0/host/processes", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	if !strings.Contains(w.Body.String(), `"cmdline":"cron"`) {
+		t.Errorf("%s does not contain host process", w.Body.String())
+	}
+}
+
+func TestAPIHandlerRecursive(t *testing.T) {
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", "http://localhost:8801/api/v1.0/docker/processes?recursive=true", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Fatalf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	var result []proc.Snapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	// including zombie sshd (8350)
+	expectedProcesses := 8
+	if len(result) != 1 || len(result[0].Processes) != expectedProcesses {
+		t.Errorf("%v does not contain expected %d processes", result, expectedProcesses)
+	}
+}
+
+func TestAPIHandlerNotRecursive(t *testing.T) {
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", "http://localhost:8801/api/v1.0/docker/processes", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	// parent cgroup has no processes of its own
+	expectedCode := 500
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+}
+
+func TestAPIHandlerCgroupStats(t *testing.T) {
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes", containerName), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	var result []proc.Snapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	if len(result) != 1 || result[0].Cgroup == nil {
+		t.Fatalf("%v has no cgroup stats", result)
+	}
+	expectedThrottled := uint64(1523)
+	if result[0].Cgroup.NrThrottled != expectedThrottled {
+		t.Errorf("%d not equal to expected %d", result[0].Cgroup.NrThrottled, expectedThrottled)
+	}
+}
+
+func TestAPIHandlerUserFilter(t *testing.T) {
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	users := map[string]int{
+		"syslog": 1,
+		"101":    1,
+		"root":   2,
+		"nobody": 0,
+	}
+	for user, expectedLen := range users {
+		u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?user=%s", containerName, user)
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		apiHandler(w, req)
+		var result []proc.Snapshot
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal("decoding response failed", err)
+		}
+		if len(result) != 1 || len(result[0].Processes) != expectedLen {
+			t.Errorf("%v does not contain expected %d processes of %s", result, expectedLen, user)
+		}
+	}
+}
+
+func TestAPIHandlerSuspicious(t *testing.T) {
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	u := fmt.Sprintf("http://localhost:8801/api/v1.0%s/processes?suspicious=true", containerName)
+	collectData("process/testroot/")
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, req)
+	var result []proc.Snapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal("decoding response failed", err)
+	}
+	if len(result) != 1 || len(result[0].Processes) != 1 {
+		t.Fatalf("%v does not contain expected %d processes", result, 1)
+	}
+	p := result[0].Processes[0]
+	if p.Exe != "/usr/bin/perl" || !p.ExeDeleted {
+		t.Errorf("%v:%v not equal to expected %v:%v", p.Exe, p.ExeDeleted, "/usr/bin/perl", true)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	proc "github.com/abulimov/cadvisor-companion/process"
+)
+
+// supported output formats
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatText = "text"
+)
+
+// contentTypes maps output formats to response Content-Type
+var contentTypes = map[string]string{
+	formatJSON: "text/json",
+	formatCSV:  "text/csv",
+	formatText: "text/plain",
+}
+
+// acceptFormats maps Accept header media types to output formats
+var acceptFormats = map[string]string{
+	"application/json": formatJSON,
+	"text/json":        formatJSON,
+	"text/csv":         formatCSV,
+	"text/plain":       formatText,
+	"*/*":              formatJSON,
+}
+
+// psHeader is the same column set as examples/ps.py uses
+var psHeader = []string{"USER", "PID", "%CPU", "%MEM", "VSZ", "RSS", "STAT", "COMMAND"}
+
+// getFormat returns output format requested either with `format`
+// get parameter or with Accept header, defaulting to JSON
+func getFormat(req *http.Request) (string, error) {
+	if f := req.URL.Query().Get("format"); f != "" {
+		if _, ok := contentTypes[f]; !ok {
+			return "", fmt.Errorf("Unknown format %s", f)
+		}
+		return f, nil
+	}
+	// pick known media type with the highest quality,
+	// first one wins if qualities are equal
+	format := formatJSON
+	bestQuality := 0.0
+	for _, a := range strings.Split(req.Header.Get("Accept"), ",") {
+		parts := strings.Split(a, ";")
+		f, ok := acceptFormats[strings.TrimSpace(parts[0])]
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > bestQuality {
+			format = f
+			bestQuality = quality
+		}
+	}
+	return format, nil
+}
+
+// memPercent returns VmRSS of process as percent of host memory
+func memPercent(p proc.Process) float64 {
+	total := atomic.LoadUint64(&memTotal)
+	if total == 0 {
+		return 0
+	}
+	return float64(p.Status.VmRSS) * 100 / float64(total)
+}
+
+// userName returns user name of process, or its UID if name is unknown
+func userName(p proc.Process) string {
+	if p.User != "" {
+		return p.User
+	}
+	return strconv.FormatUint(p.Status.RealUid, 10)
+}
+
+// writeJSON writes snapshots as JSON array
+func writeJSON(w io.Writer, result []proc.Snapshot) error {
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(jsonResult)
+	return err
+}
+
+// writeRangeJSON writes snapshots as JSON object along with
+// cursor for the next range query
+func writeRangeJSON(res http.ResponseWriter, cursor string, result []proc.Snapshot) error {
+	res.Header().Set("Content-Type", contentTypes[formatJSON])
+	jsonResult, err := json.Marshal(struct {
+		Cursor    string          `json:"cursor"`
+		Snapshots []proc.Snapshot `json:"snapshots"`
+	}{cursor, result})
+	if err != nil {
+		return err
+	}
+	_, err = res.Write(jsonResult)
+	return err
+}
+
+// writeCSV writes snapshots as CSV, one process per row
+func writeCSV(w io.Writer, result []proc.Snapshot) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"TIMESTAMP"}, psHeader...)); err != nil {
+		return err
+	}
+	for _, s := range result {
+		ts := s.Timestamp.Format(time.RFC3339Nano)
+		for _, p := range s.Processes {
+			err := cw.Write([]string{
+				ts,
+				userName(p),
+				strconv.FormatUint(p.Stat.Pid, 10),
+				strconv.FormatFloat(p.RelativeCPUUsage, 'f', 1, 64),
+				strconv.FormatFloat(memPercent(p), 'f', 1, 64),
+				strconv.FormatUint(p.Status.VmSize, 10),
+				strconv.FormatUint(p.Status.VmRSS, 10),
+				p.Stat.State,
+				p.Cmdline,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeText writes snapshots as `ps aux`-like tables
+func writeText(w io.Writer, result []proc.Snapshot) error {
+	for i, s := range result {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# %s\n", s.Timestamp.Format(time.RFC3339Nano))
+		fmt.Fprintf(w, "%5s %5s %5s %5s %8s %8s %6s %s\n",
+			psHeader[0], psHeader[1], psHeader[2], psHeader[3],
+			psHeader[4], psHeader[5], psHeader[6], psHeader[7])
+		for _, p := range s.Processes {
+			_, err := fmt.Fprintf(w, "%5s %5d %5.1f %5.1f %8d %8d %6s %s\n",
+				userName(p),
+				p.Stat.Pid,
+				p.RelativeCPUUsage,
+				memPercent(p),
+				p.Status.VmSize,
+				p.Status.VmRSS,
+				p.Stat.State,
+				p.Cmdline)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeResult writes snapshots to response in requested format
+func writeResult(res http.ResponseWriter, format string, result []proc.Snapshot) error {
+	res.Header().Set("Content-Type", contentTypes[format])
+	switch format {
+	case formatCSV:
+		return writeCSV(res, result)
+	case formatText:
+		return writeText(res, result)
+	}
+	return writeJSON(res, result)
+}
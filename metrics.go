@@ -0,0 +1,119 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	proc "github.com/abulimov/cadvisor-companion/process"
+)
+
+const metricsPrefix = "cadvisor_companion_"
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+// metricsWriter writes metrics in Prometheus text exposition format
+type metricsWriter struct {
+	w io.Writer
+}
+
+// family writes metric family header
+func (m metricsWriter) family(name, typ, help string) {
+	fmt.Fprintf(m.w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, typ)
+}
+
+// sample writes single sample, labels are given as name, value pairs
+func (m metricsWriter) sample(name string, value float64, labels ...string) {
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	fmt.Fprintf(m.w, "%s%s{%s} %g\n", metricsPrefix, name, strings.Join(pairs, ","), value)
+}
+
+// writeMetrics writes metrics of the latest history entry
+func writeMetrics(w io.Writer, entry proc.HistoryEntry, containers []string) {
+	m := metricsWriter{w}
+
+	m.family("container_processes", "gauge", "Number of processes in container.")
+	for _, c := range containers {
+		m.sample("container_processes", float64(len(entry[c].Processes)), "container", c)
+	}
+
+	m.family("container_zombies", "gauge", "Number of zombie processes in container.")
+	for _, c := range containers {
+		m.sample("container_zombies", float64(len(entry[c].Processes.Zombies())), "container", c)
+	}
+
+	m.family("container_threads", "gauge", "Number of threads in container.")
+	for _, c := range containers {
+		m.sample("container_threads", float64(entry[c].Processes.GetAggregate().Threads), "container", c)
+	}
+
+	m.family("container_rss_bytes", "gauge", "Summed RSS of container processes.")
+	for _, c := range containers {
+		m.sample("container_rss_bytes", float64(entry[c].Processes.GetAggregate().VmRSS*1024), "container", c)
+	}
+
+	m.family("container_pressure_avg10", "gauge", "Pressure Stall Information 10 seconds average, percent.")
+	writePressure(m, entry, containers, "container_pressure_avg10", func(l proc.PressureLine) float64 {
+		return l.Avg10
+	})
+	m.family("container_pressure_avg60", "gauge", "Pressure Stall Information 60 seconds average, percent.")
+	writePressure(m, entry, containers, "container_pressure_avg60", func(l proc.PressureLine) float64 {
+		return l.Avg60
+	})
+	m.family("container_pressure_stalled_seconds_total", "counter", "Total time tasks were stalled on resource.")
+	writePressure(m, entry, containers, "container_pressure_stalled_seconds_total", func(l proc.PressureLine) float64 {
+		return float64(l.Total) / 1e6
+	})
+}
+
+// writeSchedMetrics writes scheduler metrics of the latest history
+// entry, calculated since `prev` entry
+func writeSchedMetrics(w io.Writer, entry, prev proc.HistoryEntry, containers []string) {
+	m := metricsWriter{w}
+	m.family("container_sched_wait_ratio", "gauge", "Run queue wait time to run time ratio of container processes over the last interval.")
+	for _, c := range containers {
+		sched := entry[c].Processes.GetSchedDelta(prev[c].Processes)
+		m.sample("container_sched_wait_ratio", sched.WaitRatio, "container", c)
+	}
+}
+
+// writePressure writes samples for all containers, resources
+// and some/full lines of Pressure Stall Information
+func writePressure(m metricsWriter, entry proc.HistoryEntry, containers []string, name string, value func(proc.PressureLine) float64) {
+	for _, c := range containers {
+		stats := entry[c].Pressure
+		if stats == nil {
+			continue
+		}
+		resources := []struct {
+			name     string
+			pressure *proc.Pressure
+		}{{"cpu", stats.CPU}, {"memory", stats.Memory}, {"io", stats.IO}}
+		for _, r := range resources {
+			if r.pressure == nil {
+				continue
+			}
+			m.sample(name, value(r.pressure.Some), "container", c, "resource", r.name, "kind", "some")
+			if r.pressure.Full != nil {
+				m.sample(name, value(*r.pressure.Full), "container", c, "resource", r.name, "kind", "full")
+			}
+		}
+	}
+}
+
+// metricsHandler exposes latest data as Prometheus metrics
+func metricsHandler(res http.ResponseWriter, req *http.Request) {
+	historyLock.RLock()
+	defer historyLock.RUnlock()
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(res, history[len(history)-1], history.Containers())
+	writeSchedMetrics(res, history[len(history)-1], history[len(history)-2], history.Containers())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	proc "github.com/abulimov/cadvisor-companion/process"
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	containerName := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	collectData("process/testroot/")
+	req, err := http.NewRequest("GET", "http://localhost:8801/metrics", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	metricsHandler(w, req)
+	expectedCode := 200
+	if expectedCode != w.Code {
+		t.Errorf("%v HTTP code not equal to expected %v", w.Code, expectedCode)
+	}
+	expected := []string{
+		`cadvisor_companion_container_processes{container="` + containerName + `"} 3`,
+		`cadvisor_companion_container_zombies{container="/docker/ac4cd7be58276b4f801a86b52b83319c7e7b08e4e4eed15bbe1122355325e3e1"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(w.Body.String(), e+"\n") {
+			t.Errorf("%s does not contain expected %s", w.Body.String(), e)
+		}
+	}
+}
+
+func TestWriteMetricsPressure(t *testing.T) {
+	containerName := "/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope"
+	pressure, err := proc.ReadPressure("process/testdata/cgroupv2", containerName)
+	if err != nil {
+		t.Fatal("pressure read fail", err)
+	}
+	entry := proc.HistoryEntry{containerName: proc.Snapshot{Timestamp: time.Now(), Pressure: pressure}}
+	var b bytes.Buffer
+	writeMetrics(&b, entry, []string{containerName})
+	expected := []string{
+		`cadvisor_companion_container_pressure_avg10{container="` + containerName + `",resource="cpu",kind="some"} 12.5`,
+		`cadvisor_companion_container_pressure_stalled_seconds_total{container="` + containerName + `",resource="io",kind="full"} 7.710001`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(b.String(), e+"\n") {
+			t.Errorf("%s does not contain expected %s", b.String(), e)
+		}
+	}
+}
+
+func TestWriteSchedMetrics(t *testing.T) {
+	containerName := "/docker/test"
+	prev := proc.HistoryEntry{containerName: proc.Snapshot{Processes: proc.List{
+		{Status: linuxproc.ProcessStatus{Pid: 1}, Schedstat: &proc.Schedstat{RunTime: 1000, WaitTime: 1000}},
+	}}}
+	entry := proc.HistoryEntry{containerName: proc.Snapshot{Processes: proc.List{
+		{Status: linuxproc.ProcessStatus{Pid: 1}, Schedstat: &proc.Schedstat{RunTime: 3000, WaitTime: 2000}},
+	}}}
+	var b bytes.Buffer
+	writeSchedMetrics(&b, entry, prev, []string{containerName})
+	expected := `cadvisor_companion_container_sched_wait_ratio{container="` + containerName + `"} 0.5` + "\n"
+	if !strings.Contains(b.String(), expected) {
+		t.Errorf("%s does not contain expected %s", b.String(), expected)
+	}
+}
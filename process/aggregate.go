@@ -0,0 +1,111 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"fmt"
+	"time"
+)
+
+// Aggregate holds totals of all container processes at some moment in time
+type Aggregate struct {
+	Timestamp time.Time `json:"timestamp"`
+	Processes int       `json:"processes"`
+	Zombies   int       `json:"zombies"`
+	Threads   uint64    `json:"threads"`
+	// VmRSS is summed RSS of all processes, in kB
+	VmRSS uint64 `json:"vmrss"`
+	// CPUTimeDelta is utime + stime used by processes since
+	// the previous sample, in jiffies
+	CPUTimeDelta             uint64 `json:"cputimedelta"`
+	Majflt                   uint64 `json:"majflt"`
+	VoluntaryCtxtSwitches    uint64 `json:"voluntary_ctxt_switches"`
+	NonvoluntaryCtxtSwitches uint64 `json:"nonvoluntary_ctxt_switches"`
+	// NrThrottledDelta and ThrottledTimeDelta (in nanoseconds) show
+	// CFS throttling since the previous sample, if cgroup stats are known
+	NrThrottledDelta   uint64 `json:"nr_throttled_delta"`
+	ThrottledTimeDelta uint64 `json:"throttled_time_delta"`
+	// SchedRunTimeDelta and SchedWaitTimeDelta (in nanoseconds) show
+	// time processes spent on CPU and waiting on run queue since
+	// the previous sample, SchedWaitRatio is their ratio
+	SchedRunTimeDelta    uint64  `json:"sched_runtime_delta"`
+	SchedWaitTimeDelta   uint64  `json:"sched_waittime_delta"`
+	SchedTimeslicesDelta uint64  `json:"sched_timeslices_delta"`
+	SchedWaitRatio       float64 `json:"sched_wait_ratio"`
+}
+
+// GetAggregate returns totals for list of processes
+func (procs List) GetAggregate() Aggregate {
+	a := Aggregate{Processes: len(procs), Zombies: len(procs.Zombies())}
+	for _, p := range procs {
+		a.Threads += p.Status.Threads
+		a.VmRSS += p.Status.VmRSS
+		a.Majflt += p.Stat.Majflt
+		a.VoluntaryCtxtSwitches += p.Status.VoluntaryCtxtSwitches
+		a.NonvoluntaryCtxtSwitches += p.Status.NonvoluntaryCtxtSwitches
+	}
+	return a
+}
+
+// GetCPUTimeDelta returns CPU time used by procs since `prev` list was taken.
+// Unlike difference of GetCPUTotalUsage results, it is not affected by
+// exited processes, and processes started in between count in full
+func (procs List) GetCPUTimeDelta(prev List) uint64 {
+	delta := uint64(0)
+	for _, p := range procs {
+		used := p.Stat.Utime + p.Stat.Stime
+		if pp := prev.FindProc(p.Status.Pid); pp != nil && pp.Stat.Starttime == p.Stat.Starttime {
+			used -= pp.Stat.Utime + pp.Stat.Stime
+		}
+		delta += used
+	}
+	return delta
+}
+
+// GetAggregates returns container totals for every history entry
+// the container is present in, oldest first
+func (history *HistoryDB) GetAggregates(containerID string) ([]Aggregate, error) {
+	result := make([]Aggregate, 0)
+	for i, entry := range history {
+		snap, ok := entry[containerID]
+		if !ok {
+			continue
+		}
+		a := snap.Processes.GetAggregate()
+		a.Timestamp = snap.Timestamp
+		if i > 0 {
+			if prev, ok := history[i-1][containerID]; ok {
+				a.CPUTimeDelta = snap.Processes.GetCPUTimeDelta(prev.Processes)
+				a.setThrottlingDelta(prev.Cgroup, snap.Cgroup)
+				a.setSchedDelta(snap.Processes.GetSchedDelta(prev.Processes))
+			}
+		}
+		result = append(result, a)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("Container %s not found", containerID)
+	}
+	return result, nil
+}
+
+// setThrottlingDelta calculates CFS throttling between two cgroup stats,
+// counters are reset when container restarts, so we skip such samples
+func (a *Aggregate) setThrottlingDelta(prev, cur *CgroupStats) {
+	if prev == nil || cur == nil {
+		return
+	}
+	if cur.NrThrottled >= prev.NrThrottled && cur.ThrottledTime >= prev.ThrottledTime {
+		a.NrThrottledDelta = cur.NrThrottled - prev.NrThrottled
+		a.ThrottledTimeDelta = cur.ThrottledTime - prev.ThrottledTime
+	}
+}
+
+// setSchedDelta copies scheduler statistics deltas
+func (a *Aggregate) setSchedDelta(s Schedstat) {
+	a.SchedRunTimeDelta = s.RunTimeDelta
+	a.SchedWaitTimeDelta = s.WaitTimeDelta
+	a.SchedTimeslicesDelta = s.TimeslicesDelta
+	a.SchedWaitRatio = s.WaitRatio
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestGetAggregate(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	cgroupsMap := procs.GetCgroupsMap()
+	a := cgroupsMap["/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"].GetAggregate()
+	expectedProcesses := 3
+	if a.Processes != expectedProcesses {
+		t.Errorf("%d not equal to expected %d", a.Processes, expectedProcesses)
+	}
+	expectedVMRSS := uint64(9988 + 32 + 35008)
+	if a.VmRSS != expectedVMRSS {
+		t.Errorf("%d not equal to expected %d", a.VmRSS, expectedVMRSS)
+	}
+}
+
+func TestGetCPUTimeDelta(t *testing.T) {
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	prev := history[len(history)-2]["test"].Processes
+	cur := history[len(history)-1]["test"].Processes
+	delta := cur.GetCPUTimeDelta(prev)
+	expected := uint64(500)
+	if delta != expected {
+		t.Errorf("%d not equal to expected %d", delta, expected)
+	}
+}
+
+func TestHistoryAggregates(t *testing.T) {
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	aggregates, err := history.GetAggregates("test")
+	if err != nil {
+		t.Fatal("getting history aggregates failed", err)
+	}
+	expectedLen := 2
+	if len(aggregates) != expectedLen {
+		t.Fatalf("%d not equal to expected %d", len(aggregates), expectedLen)
+	}
+	if aggregates[0].CPUTimeDelta != 0 {
+		t.Errorf("%d not equal to expected 0", aggregates[0].CPUTimeDelta)
+	}
+	expectedDelta := uint64(500)
+	if aggregates[1].CPUTimeDelta != expectedDelta {
+		t.Errorf("%d not equal to expected %d", aggregates[1].CPUTimeDelta, expectedDelta)
+	}
+
+	_, err = history.GetAggregates("missing")
+	if err == nil {
+		t.Error("GetAggregates with wrong container name didn't failed when expected to fail")
+	}
+}
+
+func TestHistoryAggregatesThrottling(t *testing.T) {
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	prev := history[len(history)-2]["test"]
+	prev.Cgroup = &CgroupStats{NrThrottled: 10, ThrottledTime: 1000}
+	history[len(history)-2]["test"] = prev
+	cur := history[len(history)-1]["test"]
+	cur.Cgroup = &CgroupStats{NrThrottled: 15, ThrottledTime: 3500}
+	history[len(history)-1]["test"] = cur
+
+	aggregates, err := history.GetAggregates("test")
+	if err != nil {
+		t.Fatal("getting history aggregates failed", err)
+	}
+	last := aggregates[len(aggregates)-1]
+	expectedNr := uint64(5)
+	if last.NrThrottledDelta != expectedNr {
+		t.Errorf("%d not equal to expected %d", last.NrThrottledDelta, expectedNr)
+	}
+	expectedTime := uint64(2500)
+	if last.ThrottledTimeDelta != expectedTime {
+		t.Errorf("%d not equal to expected %d", last.ThrottledTimeDelta, expectedTime)
+	}
+}
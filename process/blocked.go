@@ -0,0 +1,107 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StuckSamples is the count of consecutive history entries
+// process should be in D state to be considered stuck
+const StuckSamples = 2
+
+// BlockedProcess is a process in uninterruptible sleep (D state)
+type BlockedProcess struct {
+	Pid     uint64 `json:"pid"`
+	Cmdline string `json:"cmdline"`
+	State   string `json:"state"`
+	// Wchan is the kernel function process is blocked in
+	Wchan string `json:"wchan,omitempty"`
+	// Stack is kernel stack, readable only by root
+	Stack []string `json:"stack,omitempty"`
+	// Samples is the count of consecutive history entries
+	// process is in D state, starting from Since
+	Samples int       `json:"samples"`
+	Since   time.Time `json:"since"`
+	Stuck   bool      `json:"stuck"`
+}
+
+// ReadWchan reads /proc/{pid}/wchan file, returning empty
+// string if process is not blocked
+func ReadWchan(path string) (string, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	wchan := strings.TrimSpace(string(dataBytes))
+	if wchan == "0" {
+		return "", nil
+	}
+	return wchan, nil
+}
+
+// ReadKernelStack reads /proc/{pid}/stack file, returning
+// function names with offsets, innermost first
+func ReadKernelStack(path string) ([]string, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, l := range strings.Split(string(dataBytes), "\n") {
+		// lines look like [<0>] io_schedule+0x16/0x40
+		fields := strings.Fields(l)
+		if len(fields) == 0 {
+			continue
+		}
+		result = append(result, fields[len(fields)-1])
+	}
+	return result, nil
+}
+
+// ReadKernelInfo reads wchan and kernel stack of process where readable
+func (b *BlockedProcess) ReadKernelInfo(rootPath string) {
+	path := filepath.Join(rootPath, "/proc", strconv.FormatUint(b.Pid, 10))
+	b.Wchan, _ = ReadWchan(filepath.Join(path, "wchan"))
+	b.Stack, _ = ReadKernelStack(filepath.Join(path, "stack"))
+}
+
+// GetUninterruptible returns processes in D state in the latest history
+// entry, along with the count of consecutive entries they stay in D state
+func (history *HistoryDB) GetUninterruptible(containerID string) ([]BlockedProcess, error) {
+	last := len(history) - 1
+	snap, ok := history[last][containerID]
+	if !ok {
+		return nil, fmt.Errorf("Container %s not found", containerID)
+	}
+	result := make([]BlockedProcess, 0)
+	for _, p := range snap.Processes {
+		if p.Stat.State != "D" {
+			continue
+		}
+		b := BlockedProcess{
+			Pid:     p.Status.Pid,
+			Cmdline: p.Cmdline,
+			State:   p.Stat.State,
+		}
+		for i := last; i >= 0; i-- {
+			s := history[i][containerID]
+			prev := s.Processes.FindProc(p.Status.Pid)
+			if prev == nil || prev.Stat.Starttime != p.Stat.Starttime || prev.Stat.State != "D" {
+				break
+			}
+			b.Samples++
+			b.Since = s.Timestamp
+		}
+		b.Stuck = b.Samples >= StuckSamples
+		result = append(result, b)
+	}
+	return result, nil
+}
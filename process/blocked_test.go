@@ -0,0 +1,79 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadWchan(t *testing.T) {
+	wchans := map[string]string{"8355": "io_schedule", "8743": ""}
+	for pid, expected := range wchans {
+		wchan, err := ReadWchan("./testroot/proc/" + pid + "/wchan")
+		if err != nil {
+			t.Fatal("wchan read fail", err)
+		}
+		if wchan != expected {
+			t.Errorf("%v not equal to expected %v", wchan, expected)
+		}
+	}
+}
+
+func TestReadKernelStack(t *testing.T) {
+	stack, err := ReadKernelStack("./testroot/proc/8355/stack")
+	if err != nil {
+		t.Fatal("stack read fail", err)
+	}
+	if len(stack) != 8 || stack[0] != "io_schedule+0x16/0x40" {
+		t.Errorf("%v is not expected stack", stack)
+	}
+	if _, err := ReadKernelStack("./testroot/proc/8743/stack"); err == nil {
+		t.Error("ReadKernelStack for missing file didn't failed when expected to fail")
+	}
+}
+
+func TestGetUninterruptible(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	containerID := "/docker/ac4cd7be58276b4f801a86b52b83319c7e7b08e4e4eed15bbe1122355325e3e1"
+	procs = procs.GetCgroupsMap()[containerID]
+	var history HistoryDB
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		history.Push(HistoryEntry{containerID: Snapshot{
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Processes: procs,
+		}})
+	}
+	result, err := history.GetUninterruptible(containerID)
+	if err != nil {
+		t.Fatal("uninterruptible processes fail", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("%d not equal to expected %d", len(result), 1)
+	}
+	b := result[0]
+	if b.Pid != 8355 || b.Samples != 3 || !b.Stuck || !b.Since.Equal(now) {
+		t.Errorf("%v is not expected blocked process", b)
+	}
+	b.ReadKernelInfo("./testroot")
+	if b.Wchan != "io_schedule" || len(b.Stack) != 8 {
+		t.Errorf("%v is not expected blocked process kernel info", b)
+	}
+
+	// process that just entered D state is not stuck yet
+	history.Push(HistoryEntry{containerID: Snapshot{Timestamp: now}})
+	history.Push(HistoryEntry{containerID: Snapshot{Timestamp: now, Processes: procs}})
+	result, _ = history.GetUninterruptible(containerID)
+	if len(result) != 1 || result[0].Samples != 1 || result[0].Stuck {
+		t.Errorf("%v is not expected blocked process", result)
+	}
+	if _, err := history.GetUninterruptible("/docker/missing"); err == nil {
+		t.Error("GetUninterruptible for missing container didn't failed when expected to fail")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CgroupStats holds cgroup-level CPU and memory accounting,
+// which shows things per-process data can't, like CFS throttling
+type CgroupStats struct {
+	// CPUUsage is total CPU time consumed by cgroup, in nanoseconds
+	CPUUsage    uint64 `json:"cpuusage"`
+	NrPeriods   uint64 `json:"nr_periods"`
+	NrThrottled uint64 `json:"nr_throttled"`
+	// ThrottledTime is total time cgroup was throttled, in nanoseconds
+	ThrottledTime uint64 `json:"throttled_time"`
+	// RSS, Cache and Swap are in bytes
+	RSS   uint64 `json:"rss"`
+	Cache uint64 `json:"cache"`
+	Swap  uint64 `json:"swap"`
+	// Failcnt is the number of times memory limit was hit
+	Failcnt uint64 `json:"failcnt"`
+	OOMKill uint64 `json:"oom_kill"`
+}
+
+// cgroupRoot returns where cgroup filesystem is mounted
+func cgroupRoot(rootPath string) string {
+	return filepath.Join(rootPath, "/sys/fs/cgroup")
+}
+
+// IsCgroupV2 tells if host uses cgroup v2 unified hierarchy
+func IsCgroupV2(rootPath string) bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot(rootPath), "cgroup.controllers"))
+	return err == nil
+}
+
+// readKeyValues reads flat keyed files like cpu.stat and memory.stat
+func readKeyValues(path string) (map[string]uint64, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]uint64)
+	for _, l := range strings.Split(string(dataBytes), "\n") {
+		fields := strings.Fields(l)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			result[fields[0]] = v
+		}
+	}
+	return result, nil
+}
+
+// readUint reads file with single number, like cpuacct.usage
+func readUint(path string) (uint64, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(dataBytes)), 10, 64)
+}
+
+// ReadCgroupStats reads CPU and memory accounting files of cgroup,
+// for both cgroup v1 and v2 hosts. Missing files are skipped,
+// as their set depends on kernel version
+func ReadCgroupStats(rootPath, cgroup string) (*CgroupStats, error) {
+	var stats CgroupStats
+	var found bool
+	if IsCgroupV2(rootPath) {
+		found = stats.readV2(filepath.Join(cgroupRoot(rootPath), cgroup))
+	} else {
+		found = stats.readV1(cgroupRoot(rootPath), cgroup)
+	}
+	if !found {
+		return nil, errors.New("No accounting files found for cgroup " + cgroup)
+	}
+	return &stats, nil
+}
+
+func (stats *CgroupStats) readV1(root, cgroup string) bool {
+	found := false
+	if v, err := readUint(filepath.Join(root, "cpuacct", cgroup, "cpuacct.usage")); err == nil {
+		stats.CPUUsage = v
+		found = true
+	}
+	if m, err := readKeyValues(filepath.Join(root, "cpu", cgroup, "cpu.stat")); err == nil {
+		stats.NrPeriods = m["nr_periods"]
+		stats.NrThrottled = m["nr_throttled"]
+		stats.ThrottledTime = m["throttled_time"]
+		found = true
+	}
+	memory := filepath.Join(root, "memory", cgroup)
+	if m, err := readKeyValues(filepath.Join(memory, "memory.stat")); err == nil {
+		stats.RSS = m["rss"]
+		stats.Cache = m["cache"]
+		stats.Swap = m["swap"]
+		found = true
+	}
+	if v, err := readUint(filepath.Join(memory, "memory.failcnt")); err == nil {
+		stats.Failcnt = v
+	}
+	// oom_kill is reported since Linux 4.13
+	if m, err := readKeyValues(filepath.Join(memory, "memory.oom_control")); err == nil {
+		stats.OOMKill = m["oom_kill"]
+	}
+	return found
+}
+
+func (stats *CgroupStats) readV2(dir string) bool {
+	found := false
+	if m, err := readKeyValues(filepath.Join(dir, "cpu.stat")); err == nil {
+		stats.CPUUsage = m["usage_usec"] * 1000
+		stats.NrPeriods = m["nr_periods"]
+		stats.NrThrottled = m["nr_throttled"]
+		stats.ThrottledTime = m["throttled_usec"] * 1000
+		found = true
+	}
+	if m, err := readKeyValues(filepath.Join(dir, "memory.stat")); err == nil {
+		stats.RSS = m["anon"]
+		stats.Cache = m["file"]
+		found = true
+	}
+	if v, err := readUint(filepath.Join(dir, "memory.swap.current")); err == nil {
+		stats.Swap = v
+	}
+	// hitting memory.max is the closest thing to v1 failcnt
+	if m, err := readKeyValues(filepath.Join(dir, "memory.events")); err == nil {
+		stats.Failcnt = m["max"]
+		stats.OOMKill = m["oom_kill"]
+	}
+	return found
+}
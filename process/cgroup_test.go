@@ -0,0 +1,58 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestReadCgroupStatsV1(t *testing.T) {
+	stats, err := ReadCgroupStats("./testroot", "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a")
+	if err != nil {
+		t.Fatal("cgroup stats read fail", err)
+	}
+	expected := CgroupStats{
+		CPUUsage:      1873264511893,
+		NrPeriods:     86400,
+		NrThrottled:   1523,
+		ThrottledTime: 95871203391,
+		RSS:           46317568,
+		Cache:         21344256,
+		Swap:          1048576,
+		Failcnt:       12,
+		OOMKill:       1,
+	}
+	if *stats != expected {
+		t.Errorf("%v not equal to expected %v", *stats, expected)
+	}
+}
+
+func TestReadCgroupStatsV2(t *testing.T) {
+	stats, err := ReadCgroupStats("./testdata/cgroupv2", "/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope")
+	if err != nil {
+		t.Fatal("cgroup stats read fail", err)
+	}
+	expected := CgroupStats{
+		CPUUsage:      5871203000,
+		NrPeriods:     1200,
+		NrThrottled:   37,
+		ThrottledTime: 2500000000,
+		RSS:           33554432,
+		Cache:         8388608,
+		Swap:          2097152,
+		Failcnt:       5,
+		OOMKill:       2,
+	}
+	if *stats != expected {
+		t.Errorf("%v not equal to expected %v", *stats, expected)
+	}
+}
+
+func TestReadCgroupStatsMissing(t *testing.T) {
+	_, err := ReadCgroupStats("./testroot", "/docker/missing")
+	if err == nil {
+		t.Error("ReadCgroupStats for missing cgroup didn't failed when expected to fail")
+	}
+}
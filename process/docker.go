@@ -0,0 +1,78 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// DockerContainer holds Docker container metadata
+type DockerContainer struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Image  string            `json:"image"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// NetworkMode and MemoryLimit (in bytes) come from hostconfig.json
+	NetworkMode string `json:"networkmode,omitempty"`
+	MemoryLimit int64  `json:"memorylimit,omitempty"`
+}
+
+// dockerConfig is the part of config.v2.json we care about
+type dockerConfig struct {
+	ID     string
+	Name   string
+	Config struct {
+		Image  string
+		Labels map[string]string
+	}
+}
+
+// dockerHostConfig is the part of hostconfig.json we care about
+type dockerHostConfig struct {
+	NetworkMode string
+	Memory      int64
+}
+
+// DockerID returns Docker container id for cgroups like /docker/{id}
+// and /system.slice/docker-{id}.scope
+func DockerID(cgroup string) (string, bool) {
+	ref, ok := NormalizeCgroup(cgroup)
+	if !ok || ref.Runtime != "docker" {
+		return "", false
+	}
+	return ref.ID, true
+}
+
+// ReadDockerContainer reads container metadata right from Docker's state
+// directory, so no access to Docker socket is needed
+func ReadDockerContainer(rootPath, id string) (*DockerContainer, error) {
+	dir := filepath.Join(rootPath, "/var/lib/docker/containers", id)
+	dataBytes, err := ioutil.ReadFile(filepath.Join(dir, "config.v2.json"))
+	if err != nil {
+		return nil, err
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(dataBytes, &config); err != nil {
+		return nil, err
+	}
+	c := DockerContainer{
+		ID:     config.ID,
+		Name:   strings.TrimPrefix(config.Name, "/"),
+		Image:  config.Config.Image,
+		Labels: config.Config.Labels,
+	}
+	// hostconfig.json is optional, name and image are what we need most
+	if dataBytes, err = ioutil.ReadFile(filepath.Join(dir, "hostconfig.json")); err == nil {
+		var hostConfig dockerHostConfig
+		if err := json.Unmarshal(dataBytes, &hostConfig); err == nil {
+			c.NetworkMode = hostConfig.NetworkMode
+			c.MemoryLimit = hostConfig.Memory
+		}
+	}
+	return &c, nil
+}
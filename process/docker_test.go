@@ -0,0 +1,56 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestDockerID(t *testing.T) {
+	id := "7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad"
+	cgroups := map[string]string{
+		"/docker/" + id:                         id,
+		"/system.slice/docker-" + id + ".scope": id,
+		"/user/0.user/522.session":              "",
+		"/docker":                               "",
+	}
+	for cgroup, expected := range cgroups {
+		got, ok := DockerID(cgroup)
+		if got != expected || ok != (expected != "") {
+			t.Errorf("%s not equal to expected %s for %s", got, expected, cgroup)
+		}
+	}
+}
+
+func TestReadDockerContainer(t *testing.T) {
+	id := "325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	c, err := ReadDockerContainer("./testroot", id)
+	if err != nil {
+		t.Fatal("docker container read fail", err)
+	}
+	expectedName := "munin-node"
+	if c.Name != expectedName {
+		t.Errorf("%s not equal to expected %s", c.Name, expectedName)
+	}
+	expectedImage := "abulimov/munin-node:latest"
+	if c.Image != expectedImage {
+		t.Errorf("%s not equal to expected %s", c.Image, expectedImage)
+	}
+	expectedLabel := "monitoring"
+	if c.Labels["com.example.team"] != expectedLabel {
+		t.Errorf("%s not equal to expected %s", c.Labels["com.example.team"], expectedLabel)
+	}
+	expectedLimit := int64(268435456)
+	if c.MemoryLimit != expectedLimit {
+		t.Errorf("%d not equal to expected %d", c.MemoryLimit, expectedLimit)
+	}
+}
+
+func TestReadDockerContainerMissing(t *testing.T) {
+	_, err := ReadDockerContainer("./testroot", "020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0")
+	if err == nil {
+		t.Error("ReadDockerContainer for missing container didn't failed when expected to fail")
+	}
+}
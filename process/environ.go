@@ -0,0 +1,81 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// RedactedValue replaces values of redacted variables
+const RedactedValue = "<redacted>"
+
+// DefaultRedactPatterns match keys of variables usually holding credentials
+var DefaultRedactPatterns = []string{"*PASSWORD*", "*TOKEN*", "*SECRET*"}
+
+// DefaultAllowPatterns match keys of common variables safe to show
+var DefaultAllowPatterns = []string{"PATH", "HOME", "HOSTNAME", "USER", "SHELL", "TERM", "LANG", "LC_*", "TZ"}
+
+// EnvVar is a single environment variable of process
+type EnvVar struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Redacted bool   `json:"redacted,omitempty"`
+}
+
+// Redactor hides values of all environment variables, except ones with
+// keys matching Allow list and not matching Patterns. Both are shell patterns,
+// matched case-insensitively
+type Redactor struct {
+	Patterns []string
+	Allow    []string
+}
+
+// ReadEnviron parses /proc/{pid}/environ file
+func ReadEnviron(path string) ([]EnvVar, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]EnvVar, 0)
+	for _, v := range strings.Split(string(dataBytes), "\x00") {
+		if v == "" {
+			continue
+		}
+		parts := strings.SplitN(v, "=", 2)
+		e := EnvVar{Key: parts[0]}
+		if len(parts) == 2 {
+			e.Value = parts[1]
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// matchAny tells if key matches any of patterns
+func matchAny(patterns []string, key string) bool {
+	key = strings.ToUpper(key)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact replaces values of variables which are not allowed,
+// or allowed but matching Patterns, with RedactedValue
+func (r Redactor) Redact(vars []EnvVar) []EnvVar {
+	result := make([]EnvVar, 0, len(vars))
+	for _, v := range vars {
+		if !matchAny(r.Allow, v.Key) || matchAny(r.Patterns, v.Key) {
+			v.Value = RedactedValue
+			v.Redacted = true
+		}
+		result = append(result, v)
+	}
+	return result
+}
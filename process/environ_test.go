@@ -0,0 +1,64 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestReadEnviron(t *testing.T) {
+	vars, err := ReadEnviron("./testroot/proc/8743/environ")
+	if err != nil {
+		t.Fatal("environ read fail", err)
+	}
+	expectedLen := 8
+	if len(vars) != expectedLen {
+		t.Fatalf("%d not equal to expected %d", len(vars), expectedLen)
+	}
+	expected := EnvVar{Key: "HOME", Value: "/root"}
+	if vars[2] != expected {
+		t.Errorf("%v not equal to expected %v", vars[2], expected)
+	}
+	if _, err := ReadEnviron("./testroot/proc/2309/environ"); err == nil {
+		t.Error("ReadEnviron for missing file didn't failed when expected to fail")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	vars, err := ReadEnviron("./testroot/proc/8743/environ")
+	if err != nil {
+		t.Fatal("environ read fail", err)
+	}
+	// TOKEN_URL is allowed, but still matches *TOKEN* pattern
+	r := Redactor{Patterns: DefaultRedactPatterns, Allow: []string{"PATH", "home", "HOST*", "TOKEN_URL"}}
+	expected := map[string]string{
+		"PATH":           "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOSTNAME":       "325898765f2a",
+		"HOME":           "/root",
+		"DB_PASSWORD":    RedactedValue,
+		"github_token":   RedactedValue,
+		"APP_SECRET_KEY": RedactedValue,
+		"TOKEN_URL":      RedactedValue,
+		"EMPTY":          RedactedValue,
+	}
+	for _, v := range r.Redact(vars) {
+		if v.Value != expected[v.Key] {
+			t.Errorf("%v not equal to expected %v for %v", v.Value, expected[v.Key], v.Key)
+		}
+		if v.Redacted != (v.Value == RedactedValue) {
+			t.Errorf("%v redacted flag is wrong", v)
+		}
+	}
+	// everything is redacted unless allowed
+	for _, v := range (Redactor{}).Redact(vars) {
+		if !v.Redacted {
+			t.Errorf("%v is not redacted", v)
+		}
+	}
+	// original values are kept intact
+	if vars[3].Value != "hunter2" {
+		t.Errorf("%v not equal to expected %v", vars[3].Value, "hunter2")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// deletedSuffix is added by kernel to links of deleted files
+const deletedSuffix = " (deleted)"
+
+// writableLocations are world-writable directories where
+// dropped binaries like crypto miners usually live
+var writableLocations = []string{"/tmp/", "/var/tmp/", "/dev/shm/"}
+
+// ProcessPaths holds process executable, working directory and root,
+// as they are seen in process mount namespace
+type ProcessPaths struct {
+	Exe string `json:"exe,omitempty"`
+	// ExeDeleted is set if executable was deleted or replaced after start
+	ExeDeleted bool   `json:"exedeleted,omitempty"`
+	Cwd        string `json:"cwd,omitempty"`
+	Root       string `json:"root,omitempty"`
+}
+
+// ReadProcessPaths reads /proc/{pid}/exe, cwd and root links,
+// path is /proc/{pid}. Links of kernel threads and processes
+// of other users are not readable and are left empty
+func ReadProcessPaths(path string) ProcessPaths {
+	var paths ProcessPaths
+	paths.Exe, _ = os.Readlink(filepath.Join(path, "exe"))
+	if strings.HasSuffix(paths.Exe, deletedSuffix) {
+		paths.Exe = strings.TrimSuffix(paths.Exe, deletedSuffix)
+		paths.ExeDeleted = true
+	}
+	paths.Cwd, _ = os.Readlink(filepath.Join(path, "cwd"))
+	paths.Root, _ = os.Readlink(filepath.Join(path, "root"))
+	return paths
+}
+
+// IsWritableLocation tells if file is in world-writable directory
+func IsWritableLocation(file string) bool {
+	for _, dir := range writableLocations {
+		if strings.HasPrefix(file, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Suspicious tells if process executable was deleted
+// or lives in world-writable directory
+func (p Process) Suspicious() bool {
+	return p.ExeDeleted || IsWritableLocation(p.Exe)
+}
+
+// Suspicious returns processes with suspicious executables
+func (procs List) Suspicious() List {
+	var result List
+	for _, p := range procs {
+		if p.Suspicious() {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestReadProcessPaths(t *testing.T) {
+	expected := map[string]ProcessPaths{
+		"8743":  {Exe: "/usr/sbin/rsyslogd", Cwd: "/"},
+		"8713":  {Exe: "/usr/bin/perl", ExeDeleted: true, Cwd: "/var/lib/munin"},
+		"22291": {Exe: "/tmp/kdevtmpfsi", Cwd: "/tmp", Root: "/"},
+		"2309":  {},
+	}
+	for pid, e := range expected {
+		paths := ReadProcessPaths("./testroot/proc/" + pid)
+		if paths != e {
+			t.Errorf("%v not equal to expected %v", paths, e)
+		}
+	}
+}
+
+func TestIsWritableLocation(t *testing.T) {
+	files := map[string]bool{
+		"/tmp/kdevtmpfsi":    true,
+		"/dev/shm/.x/miner":  true,
+		"/usr/bin/perl":      false,
+		"/tmpfs/bin/app":     false,
+		"/var/tmp/.ICE-unix": true,
+	}
+	for file, expected := range files {
+		if IsWritableLocation(file) != expected {
+			t.Errorf("%v not equal to expected %v for %v", !expected, expected, file)
+		}
+	}
+}
+
+func TestSuspicious(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	suspicious := procs.Suspicious()
+	expectedPids := map[uint64]bool{8713: true, 22291: true}
+	if len(suspicious) != len(expectedPids) {
+		t.Fatalf("%d not equal to expected %d", len(suspicious), len(expectedPids))
+	}
+	for _, p := range suspicious {
+		if !expectedPids[p.Status.Pid] {
+			t.Errorf("%d is not expected to be suspicious", p.Status.Pid)
+		}
+	}
+}
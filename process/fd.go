@@ -0,0 +1,164 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+)
+
+// FDStats holds open file descriptors counts of process.
+// Breakdown by type is only filled by ReadProcessFDs
+type FDStats struct {
+	Total      uint64 `json:"total"`
+	Sockets    uint64 `json:"sockets,omitempty"`
+	Pipes      uint64 `json:"pipes,omitempty"`
+	Files      uint64 `json:"files,omitempty"`
+	AnonInodes uint64 `json:"anoninodes,omitempty"`
+	// Devices are /dev entries like /dev/null and ttys
+	Devices uint64 `json:"devices,omitempty"`
+	// SoftLimit and HardLimit are RLIMIT_NOFILE values, 0 means unlimited
+	SoftLimit uint64 `json:"softlimit"`
+	HardLimit uint64 `json:"hardlimit"`
+	// Usage is Total as percent of SoftLimit
+	Usage float64 `json:"usage"`
+}
+
+// ByFDs helps us sort array of Process by open file descriptors count
+type ByFDs List
+
+func (p ByFDs) Len() int {
+	return len(p)
+}
+func (p ByFDs) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+func (p ByFDs) Less(i, j int) bool {
+	return p[i].FDs.total() < p[j].FDs.total()
+}
+
+// total returns total count of fds, processes with unreadable fds
+// are treated as having none
+func (fds *FDStats) total() uint64 {
+	if fds == nil {
+		return 0
+	}
+	return fds.Total
+}
+
+// TopFDs returns `limit` processes with top open file descriptors count
+func (procs List) TopFDs(limit int) List {
+	return procs.top(ByFDs(procs), limit)
+}
+
+// ReadProcessLimit reads soft and hard limit with given name,
+// like "Max open files", from /proc/{pid}/limits file
+func ReadProcessLimit(path, name string) (uint64, uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		l := scanner.Text()
+		if !strings.HasPrefix(l, name+" ") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(l, name))
+		if len(fields) < 2 {
+			break
+		}
+		// unlimited is parsed to 0
+		soft, _ := strconv.ParseUint(fields[0], 10, 64)
+		hard, _ := strconv.ParseUint(fields[1], 10, 64)
+		return soft, hard, nil
+	}
+	return 0, 0, errors.New("No " + name + " limit in " + path)
+}
+
+// readFDNames returns names of entries of /proc/{pid}/fd without
+// stat-ing them, which is costly for processes with many fds
+func readFDNames(path string) ([]string, error) {
+	d, err := os.Open(filepath.Join(path, "fd"))
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	return d.Readdirnames(-1)
+}
+
+// setLimits reads RLIMIT_NOFILE from /proc/{pid}/limits and computes Usage
+func (fds *FDStats) setLimits(path string) {
+	fds.SoftLimit, fds.HardLimit, _ = ReadProcessLimit(filepath.Join(path, "limits"), "Max open files")
+	if fds.SoftLimit > 0 {
+		fds.Usage = float64(fds.Total) / float64(fds.SoftLimit) * 100
+	}
+}
+
+// CountProcessFDs counts entries of /proc/{pid}/fd without resolving them
+// and reads RLIMIT_NOFILE from /proc/{pid}/limits. path is /proc/{pid}.
+// It is cheap enough to be called for every process on every collection
+func CountProcessFDs(path string) (*FDStats, error) {
+	names, err := readFDNames(path)
+	if err != nil {
+		return nil, err
+	}
+	fds := FDStats{Total: uint64(len(names))}
+	fds.setLimits(path)
+	return &fds, nil
+}
+
+// ReadProcessFDs counts entries of /proc/{pid}/fd by their type
+// and reads RLIMIT_NOFILE from /proc/{pid}/limits. path is /proc/{pid}.
+// It resolves every fd link, so it is meant for a single process on demand
+func ReadProcessFDs(path string) (*FDStats, error) {
+	names, err := readFDNames(path)
+	if err != nil {
+		return nil, err
+	}
+	var fds FDStats
+	for _, name := range names {
+		// fd may be closed while we are reading
+		link, err := os.Readlink(filepath.Join(path, "fd", name))
+		if err != nil {
+			continue
+		}
+		fds.Total++
+		switch {
+		case strings.HasPrefix(link, "socket:"):
+			fds.Sockets++
+		case strings.HasPrefix(link, "pipe:"):
+			fds.Pipes++
+		case strings.HasPrefix(link, "anon_inode:"):
+			fds.AnonInodes++
+		case strings.HasPrefix(link, "/dev/"):
+			fds.Devices++
+		case strings.HasPrefix(link, "/"):
+			fds.Files++
+		}
+	}
+	fds.setLimits(path)
+	return &fds, nil
+}
+
+// ReadFDs replaces fds counts of process with their breakdown by type,
+// if the process is still running and its pid was not reused
+func (p *Process) ReadFDs(rootPath string) {
+	path := filepath.Join(rootPath, "/proc", strconv.FormatUint(p.Status.Pid, 10))
+	stat, err := linuxproc.ReadProcessStat(filepath.Join(path, "stat"))
+	if err != nil || stat.Starttime != p.Stat.Starttime {
+		return
+	}
+	if fds, err := ReadProcessFDs(path); err == nil {
+		p.FDs = fds
+	}
+}
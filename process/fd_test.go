@@ -0,0 +1,83 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestReadProcessFDs(t *testing.T) {
+	fds, err := ReadProcessFDs("./testroot/proc/8743")
+	if err != nil {
+		t.Fatal("process fds read fail", err)
+	}
+	expected := FDStats{
+		Total:      8,
+		Sockets:    2,
+		Pipes:      1,
+		Files:      1,
+		AnonInodes: 1,
+		Devices:    3,
+		SoftLimit:  16,
+		HardLimit:  4096,
+		Usage:      50,
+	}
+	if *fds != expected {
+		t.Errorf("%v not equal to expected %v", *fds, expected)
+	}
+}
+
+func TestCountProcessFDs(t *testing.T) {
+	fds, err := CountProcessFDs("./testroot/proc/8743")
+	if err != nil {
+		t.Fatal("process fds count fail", err)
+	}
+	expected := FDStats{
+		Total:     8,
+		SoftLimit: 16,
+		HardLimit: 4096,
+		Usage:     50,
+	}
+	if *fds != expected {
+		t.Errorf("%v not equal to expected %v", *fds, expected)
+	}
+	if _, err := CountProcessFDs("./testroot/proc/2309"); err == nil {
+		t.Error("CountProcessFDs for process without fd dir didn't failed when expected to fail")
+	}
+}
+
+func TestReadProcessFDsMissing(t *testing.T) {
+	_, err := ReadProcessFDs("./testroot/proc/2309")
+	if err == nil {
+		t.Error("ReadProcessFDs for process without fd dir didn't failed when expected to fail")
+	}
+}
+
+func TestReadProcessLimit(t *testing.T) {
+	soft, hard, err := ReadProcessLimit("./testroot/proc/8743/limits", "Max stack size")
+	if err != nil {
+		t.Fatal("process limits read fail", err)
+	}
+	if soft != 8388608 || hard != 0 {
+		t.Errorf("%d/%d not equal to expected %d/%d", soft, hard, 8388608, 0)
+	}
+}
+
+func TestTopFDs(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	top := procs.TopFDs(2)
+	if len(top) != 2 {
+		t.Fatalf("%d not equal to expected %d", len(top), 2)
+	}
+	expectedPids := []uint64{8743, 6930}
+	for i, p := range top {
+		if p.Status.Pid != expectedPids[i] {
+			t.Errorf("%d not equal to expected %d", p.Status.Pid, expectedPids[i])
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -18,6 +19,12 @@ type HistoryDB [60]HistoryEntry
 type Snapshot struct {
 	Timestamp time.Time `json:"timestamp"`
 	Processes List      `json:"processes"`
+	// Cgroup holds cgroup-level accounting, if it was readable
+	Cgroup *CgroupStats `json:"cgroupstats,omitempty"`
+	// Pressure holds Pressure Stall Information on cgroup v2 hosts
+	Pressure *PressureStats `json:"pressure,omitempty"`
+	// Network holds interfaces of container network namespace
+	Network *NetworkStats `json:"network,omitempty"`
 }
 
 // HistoryEntry containes all Snapshots for some moment in time
@@ -32,9 +39,10 @@ func (history *HistoryDB) Push(entry HistoryEntry) {
 	history[len(history)-1] = entry
 }
 
-// GetLastData returns data from history with added relative CPU usage
+// GetLastData returns data from history with added relative CPU usage,
+// scheduler statistics deltas and counters rates.
 // offset (in seconds) lets us get data from the past.
-// interval (in seconds) is used to calculate CPU usage
+// interval (in seconds) is used to calculate CPU usage, deltas and rates
 func (history *HistoryDB) GetLastData(containerID string, interval, offset int) (*Snapshot, error) {
 	if len(history) < offset+interval || offset < 1 || interval < 1 {
 		return nil, errors.New("Wrong offset and interval combination")
@@ -47,20 +55,35 @@ func (history *HistoryDB) GetLastData(containerID string, interval, offset int)
 		return nil, fmt.Errorf("Container %s not found", containerID)
 	}
 	var procs List
-	cpu1 := entry1.Processes.GetCPUTotalUsage()
-	cpu2 := entry2.Processes.GetCPUTotalUsage()
+	seconds := entry2.Timestamp.Sub(entry1.Timestamp).Seconds()
+	// totals of whole entries shift when processes exit during
+	// the interval, so only matched processes are counted
+	cpuDelta := entry2.Processes.GetCPUTimeDelta(entry1.Processes)
 	for _, p2 := range entry2.Processes {
 		p1 := entry1.Processes.FindProc(p2.Status.Pid)
-		if p1 != nil {
-			user := int64(p2.Stat.Utime - p1.Stat.Utime)
-			system := int64(p2.Stat.Stime - p1.Stat.Stime)
-			percent := (float64(user+system) / float64(cpu2-cpu1)) * 100
-			p2.RelativeCPUUsage = percent
+		if p1 == nil {
+			continue
+		}
+		// PID may be reused by another process during the interval,
+		// counters of such process can't be subtracted from previous ones
+		if p1.Stat.Starttime != p2.Stat.Starttime {
+			p2.Schedstat = p2.Schedstat.Delta(nil)
 			procs = append(procs, p2)
+			continue
 		}
-
+		user := int64(p2.Stat.Utime - p1.Stat.Utime)
+		system := int64(p2.Stat.Stime - p1.Stat.Stime)
+		// container may use no CPU at all during the interval
+		if cpuDelta > 0 {
+			percent := (float64(user+system) / float64(cpuDelta)) * 100
+			p2.RelativeCPUUsage = percent
+		}
+		p2.Schedstat = p2.Schedstat.Delta(p1.Schedstat)
+		p2.Rates = p2.GetRates(*p1, seconds)
+		procs = append(procs, p2)
 	}
-	return &Snapshot{entry2.Timestamp, procs}, nil
+	entry2.Processes = procs
+	return &entry2, nil
 }
 
 // GetTopCPU returns `limit` entries with top CPU usage
@@ -69,16 +92,8 @@ func (history *HistoryDB) GetTopCPU(containerID string, limit, interval, offset
 	if err != nil {
 		return nil, err
 	}
-	sort.Sort(sort.Reverse(ByCPU(entry.Processes)))
-	if limit == 0 || limit > len(entry.Processes) {
-		limit = len(entry.Processes)
-	}
-	var result Snapshot
-	result.Timestamp = entry.Timestamp
-	for _, p := range entry.Processes[:limit] {
-		result.Processes = append(result.Processes, p)
-	}
-	return &result, nil
+	entry.Processes = entry.Processes.TopCPU(limit)
+	return entry, nil
 }
 
 // GetTopMem returns `limit` entries with top VmRSS usage
@@ -87,14 +102,167 @@ func (history *HistoryDB) GetTopMem(containerID string, limit, interval, offset
 	if err != nil {
 		return nil, err
 	}
-	sort.Sort(sort.Reverse(ByRSS(entry.Processes)))
-	if limit == 0 || limit > len(entry.Processes) {
-		limit = len(entry.Processes)
+	entry.Processes = entry.Processes.TopMem(limit)
+	return entry, nil
+}
+
+// GetRange returns all data from history with timestamps after `since`
+// and not after `until`, oldest first. Zero `until` means no upper bound.
+// interval (in seconds) is used to calculate CPU usage
+func (history *HistoryDB) GetRange(containerID string, since, until time.Time, interval int) ([]Snapshot, error) {
+	if interval < 1 || interval >= len(history) {
+		return nil, errors.New("Wrong interval")
+	}
+	result := make([]Snapshot, 0)
+	for offset := len(history) - interval; offset >= 1; offset-- {
+		entry, ok := history[len(history)-offset][containerID]
+		if !ok {
+			continue
+		}
+		if !entry.Timestamp.After(since) {
+			continue
+		}
+		if !until.IsZero() && entry.Timestamp.After(until) {
+			continue
+		}
+		snap, err := history.GetLastData(containerID, interval, offset)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *snap)
+	}
+	return result, nil
+}
+
+// Newest returns timestamp of the latest history entry,
+// or zero time if history is empty
+func (history *HistoryDB) Newest() time.Time {
+	for i := len(history) - 1; i >= 0; i-- {
+		for _, s := range history[i] {
+			return s.Timestamp
+		}
+	}
+	return time.Time{}
+}
+
+// ProcessSample is a point in process resource usage time series
+type ProcessSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	// CPUTime is utime + stime of the process, in jiffies
+	CPUTime          uint64  `json:"cputime"`
+	RelativeCPUUsage float64 `json:"relativecpuusage"`
+	VmRSS            uint64  `json:"vmrss"`
+}
+
+// ProcessHistory is a single process followed across all history entries
+type ProcessHistory struct {
+	// Process is the latest known state of the process
+	Process   Process         `json:"process"`
+	FirstSeen time.Time       `json:"firstseen"`
+	Samples   []ProcessSample `json:"samples"`
+}
+
+// GetProcessHistory returns the latest state and CPU and RSS time series
+// of process with given pid across the whole history.
+// Processes are matched by pid and start time, so reused pids don't mix up
+func (history *HistoryDB) GetProcessHistory(containerID string, pid uint64) (*ProcessHistory, error) {
+	var last *Process
+	for i := len(history) - 1; i >= 0 && last == nil; i-- {
+		last = history[i][containerID].Processes.FindProc(pid)
+	}
+	if last == nil {
+		return nil, fmt.Errorf("Process %d not found in container %s", pid, containerID)
+	}
+	same := func(p *Process) bool {
+		return p != nil && p.Stat.Starttime == last.Stat.Starttime
+	}
+
+	result := &ProcessHistory{Samples: make([]ProcessSample, 0)}
+	for i, entry := range history {
+		snap := entry[containerID]
+		p := snap.Processes.FindProc(pid)
+		if !same(p) {
+			continue
+		}
+		sample := ProcessSample{
+			Timestamp: snap.Timestamp,
+			CPUTime:   p.Stat.Utime + p.Stat.Stime,
+			VmRSS:     p.Status.VmRSS,
+		}
+		if i > 0 {
+			prevSnap := history[i-1][containerID]
+			prev := prevSnap.Processes.FindProc(pid)
+			// processes which exited don't count, like in GetLastData
+			total := snap.Processes.GetCPUTimeDelta(prevSnap.Processes)
+			if same(prev) && total > 0 {
+				used := int64(sample.CPUTime - prev.Stat.Utime - prev.Stat.Stime)
+				sample.RelativeCPUUsage = float64(used) / float64(total) * 100
+			}
+		}
+		if result.FirstSeen.IsZero() {
+			result.FirstSeen = sample.Timestamp
+		}
+		result.Samples = append(result.Samples, sample)
+		result.Process = *p
+		result.Process.RelativeCPUUsage = sample.RelativeCPUUsage
+	}
+	return result, nil
+}
+
+// Containers returns sorted names of all containers in the latest history entry
+func (history *HistoryDB) Containers() []string {
+	result := make([]string, 0, len(history[len(history)-1]))
+	for name := range history[len(history)-1] {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Merged returns copy of history with processes of all containers
+// matched by `match` merged under `key` container name. Only `depth`
+// latest entries are merged, older ones are left empty. Cgroup, pressure
+// and network stats are taken from `key` container itself if it is matched,
+// as cgroup accounting already covers descendants, otherwise they are omitted
+func (history *HistoryDB) Merged(key string, depth int, match func(containerID string) bool) *HistoryDB {
+	var merged HistoryDB
+	first := len(history) - depth
+	if first < 0 {
+		first = 0
 	}
-	var result Snapshot
-	result.Timestamp = entry.Timestamp
-	for _, p := range entry.Processes[:limit] {
-		result.Processes = append(result.Processes, p)
+	for i := first; i < len(history); i++ {
+		var snap Snapshot
+		found := false
+		for containerID, s := range history[i] {
+			if !match(containerID) {
+				continue
+			}
+			found = true
+			snap.Timestamp = s.Timestamp
+			snap.Processes = append(snap.Processes, s.Processes...)
+			if containerID == key {
+				snap.Cgroup = s.Cgroup
+				snap.Pressure = s.Pressure
+				snap.Network = s.Network
+			}
+		}
+		if found {
+			merged[i] = HistoryEntry{key: snap}
+		}
 	}
-	return &result, nil
+	return &merged
+}
+
+// Subtree returns copy of `depth` latest history entries with processes
+// of container and all its descendant cgroups merged under container name
+func (history *HistoryDB) Subtree(containerID string, depth int) *HistoryDB {
+	return history.Merged(containerID, depth, func(cgroup string) bool {
+		return InSubtree(containerID, cgroup)
+	})
+}
+
+// InSubtree tells if cgroup is container itself or its descendant
+func InSubtree(containerID, cgroup string) bool {
+	prefix := strings.TrimSuffix(containerID, "/") + "/"
+	return cgroup == containerID || strings.HasPrefix(cgroup, prefix)
 }
@@ -5,6 +5,7 @@
 package process
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -18,7 +19,7 @@ func prepareHistory() (*HistoryDB, error) {
 	}
 	// create first entry
 	entry1 := make(HistoryEntry)
-	entry1["test"] = Snapshot{timeStamp1, procs1}
+	entry1["test"] = Snapshot{Timestamp: timeStamp1, Processes: procs1}
 	timeStamp2 := time.Now()
 
 	// create procs for second entry
@@ -41,7 +42,7 @@ func prepareHistory() (*HistoryDB, error) {
 
 	// create second entry
 	entry2 := make(HistoryEntry)
-	entry2["test"] = Snapshot{timeStamp2, procs2}
+	entry2["test"] = Snapshot{Timestamp: timeStamp2, Processes: procs2}
 
 	// push both entries to history
 	history.Push(entry1)
@@ -57,7 +58,7 @@ func TestHistoryPush(t *testing.T) {
 		t.Fatal("process reading fail", err)
 	}
 	entry := make(HistoryEntry)
-	entry["test"] = Snapshot{timeStamp, procs}
+	entry["test"] = Snapshot{Timestamp: timeStamp, Processes: procs}
 	// save some reference number
 	p := procs.FindProc(uint64(6930))
 	expectedCPUUserTime := p.Stat.Cutime
@@ -199,3 +200,309 @@ func TestHistoryTopMemWrongConstraints(t *testing.T) {
 		t.Error("GetTopMem with wrong limit failed when should not")
 	}
 }
+
+func TestHistoryLastDataNoCPUUsage(t *testing.T) {
+	var history HistoryDB
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	entry := make(HistoryEntry)
+	entry["test"] = Snapshot{Timestamp: time.Now(), Processes: procs}
+	history.Push(entry)
+	history.Push(entry)
+
+	snap, err := history.GetLastData("test", 1, 1)
+	if err != nil {
+		t.Fatal("getting history last data failed", err)
+	}
+	for _, p := range snap.Processes {
+		if p.RelativeCPUUsage != 0 {
+			t.Errorf("%f not equal to expected 0", p.RelativeCPUUsage)
+		}
+	}
+}
+
+func TestHistoryLastDataExitedProcess(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	survivor := *procs.FindProc(8743)
+	exited := *procs.FindProc(8713)
+	survivor.Stat.Utime, survivor.Stat.Stime = 100, 0
+	exited.Stat.Utime, exited.Stat.Stime = 1000, 0
+	var history HistoryDB
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now(), Processes: List{survivor, exited}}})
+	survivor.Stat.Utime = 110
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now(), Processes: List{survivor}}})
+
+	snap, err := history.GetLastData("test", 1, 1)
+	if err != nil {
+		t.Fatal("getting history last data failed", err)
+	}
+	// exited process doesn't count in container CPU usage
+	if len(snap.Processes) != 1 || snap.Processes[0].RelativeCPUUsage != 100 {
+		t.Errorf("%v is not expected CPU usage", snap.Processes)
+	}
+}
+
+func TestHistoryLastDataReusedPid(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	old := *procs.FindProc(8743)
+	old.Stat.Utime, old.Stat.Minflt = 1000, 1000
+	var history HistoryDB
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now(), Processes: List{old}}})
+	// new process with the same PID has lower counters
+	reused := old
+	reused.Stat.Starttime++
+	reused.Stat.Utime, reused.Stat.Minflt = 10, 10
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now().Add(time.Second), Processes: List{reused}}})
+
+	snap, err := history.GetLastData("test", 1, 1)
+	if err != nil {
+		t.Fatal("getting history last data failed", err)
+	}
+	if len(snap.Processes) != 1 {
+		t.Fatalf("%d not equal to expected %d", len(snap.Processes), 1)
+	}
+	p := snap.Processes[0]
+	if p.RelativeCPUUsage != 0 || p.Rates != nil {
+		t.Errorf("%v/%v is not expected usage of process with reused PID", p.RelativeCPUUsage, p.Rates)
+	}
+}
+
+func TestHistoryRange(t *testing.T) {
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	first := history[len(history)-2]["test"].Timestamp
+	last := history[len(history)-1]["test"].Timestamp
+
+	snaps, err := history.GetRange("test", time.Time{}, time.Time{}, 1)
+	if err != nil {
+		t.Fatal("getting history range failed", err)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("%d not equal to expected %d", len(snaps), 2)
+	}
+	if !snaps[1].Timestamp.Equal(last) {
+		t.Errorf("%s not equal to expected %s", snaps[1].Timestamp, last)
+	}
+
+	snaps, err = history.GetRange("test", first, time.Time{}, 1)
+	if err != nil {
+		t.Fatal("getting history range failed", err)
+	}
+	if len(snaps) != 1 || !snaps[0].Timestamp.Equal(last) {
+		t.Errorf("%v not equal to expected single snapshot at %s", snaps, last)
+	}
+
+	snaps, err = history.GetRange("test", time.Time{}, first, 1)
+	if err != nil {
+		t.Fatal("getting history range failed", err)
+	}
+	if len(snaps) != 1 || !snaps[0].Timestamp.Equal(first) {
+		t.Errorf("%v not equal to expected single snapshot at %s", snaps, first)
+	}
+}
+
+func TestHistoryRangeWrongConstraints(t *testing.T) {
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	_, err = history.GetRange("test", time.Time{}, time.Time{}, 100)
+	if err == nil {
+		t.Error("GetRange with wrong interval didn't failed when expected to fail")
+	}
+}
+
+func TestHistoryNewest(t *testing.T) {
+	var empty HistoryDB
+	if !empty.Newest().IsZero() {
+		t.Errorf("%s not equal to expected zero time", empty.Newest())
+	}
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	expected := history[len(history)-1]["test"].Timestamp
+	if !history.Newest().Equal(expected) {
+		t.Errorf("%s not equal to expected %s", history.Newest(), expected)
+	}
+}
+
+func TestHistoryProcessHistory(t *testing.T) {
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	ph, err := history.GetProcessHistory("test", 6930)
+	if err != nil {
+		t.Fatal("getting process history failed", err)
+	}
+	expectedLen := 2
+	if len(ph.Samples) != expectedLen {
+		t.Fatalf("%d not equal to expected %d", len(ph.Samples), expectedLen)
+	}
+	expectedFirstSeen := history[len(history)-2]["test"].Timestamp
+	if !ph.FirstSeen.Equal(expectedFirstSeen) {
+		t.Errorf("%s not equal to expected %s", ph.FirstSeen, expectedFirstSeen)
+	}
+	expectedCPUUsage := float64(100)
+	if ph.Samples[1].RelativeCPUUsage != expectedCPUUsage {
+		t.Errorf("%f not equal to expected %f", ph.Samples[1].RelativeCPUUsage, expectedCPUUsage)
+	}
+	if ph.Process.RelativeCPUUsage != expectedCPUUsage {
+		t.Errorf("%f not equal to expected %f", ph.Process.RelativeCPUUsage, expectedCPUUsage)
+	}
+	expectedVMRSS := uint64(53984)
+	if ph.Samples[0].VmRSS != expectedVMRSS {
+		t.Errorf("%d not equal to expected %d", ph.Samples[0].VmRSS, expectedVMRSS)
+	}
+}
+
+func TestHistoryProcessHistoryExitedProcess(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	survivor := *procs.FindProc(8743)
+	exited := *procs.FindProc(8713)
+	survivor.Stat.Utime, survivor.Stat.Stime = 100, 0
+	exited.Stat.Utime, exited.Stat.Stime = 1000, 0
+	var history HistoryDB
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now(), Processes: List{survivor, exited}}})
+	survivor.Stat.Utime = 110
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now(), Processes: List{survivor}}})
+
+	result, err := history.GetProcessHistory("test", 8743)
+	if err != nil {
+		t.Fatal("getting process history failed", err)
+	}
+	last := result.Samples[len(result.Samples)-1]
+	if last.RelativeCPUUsage != 100 {
+		t.Errorf("%f not equal to expected %d", last.RelativeCPUUsage, 100)
+	}
+}
+
+func TestHistoryProcessHistoryMissing(t *testing.T) {
+	history, err := prepareHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	_, err = history.GetProcessHistory("test", 1)
+	if err == nil {
+		t.Error("GetProcessHistory with wrong pid didn't failed when expected to fail")
+	}
+	_, err = history.GetProcessHistory("missing", 6930)
+	if err == nil {
+		t.Error("GetProcessHistory with wrong container name didn't failed when expected to fail")
+	}
+}
+
+func TestHistoryMerged(t *testing.T) {
+	var history HistoryDB
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	entry := make(HistoryEntry)
+	for cgroup, p := range procs.GetCgroupsMap() {
+		entry[cgroup] = Snapshot{Timestamp: time.Now(), Processes: p}
+	}
+	history.Push(entry)
+	history.Push(entry)
+
+	merged := history.Merged("merged", len(history), func(containerID string) bool {
+		return strings.HasPrefix(containerID, "/docker/")
+	})
+	snap, err := merged.GetLastData("merged", 1, 1)
+	if err != nil {
+		t.Fatal("getting merged history last data failed", err)
+	}
+	// including zombie sshd (8350)
+	expected := 8
+	if len(snap.Processes) != expected {
+		t.Errorf("%d not equal to expected %d", len(snap.Processes), expected)
+	}
+}
+
+func TestHistoryMergedStats(t *testing.T) {
+	var history HistoryDB
+	stats := &CgroupStats{}
+	pressure := &PressureStats{}
+	network := &NetworkStats{}
+	entry := HistoryEntry{
+		"/docker":     Snapshot{Timestamp: time.Now(), Cgroup: stats, Pressure: pressure, Network: network},
+		"/docker/one": Snapshot{Timestamp: time.Now(), Cgroup: &CgroupStats{}},
+	}
+	history.Push(entry)
+	history.Push(entry)
+
+	merged := history.Subtree("/docker", 1)
+	snap := merged[len(merged)-1]["/docker"]
+	if snap.Cgroup != stats || snap.Pressure != pressure || snap.Network != network {
+		t.Errorf("%v is not stats of subtree root", snap)
+	}
+	// only requested depth is merged
+	if _, ok := merged[len(merged)-2]["/docker"]; ok {
+		t.Error("entry older than depth was merged")
+	}
+
+	// stats of matched containers are not mixed under foreign key
+	pod := history.Merged("pod", 1, func(containerID string) bool { return true })
+	if snap := pod[len(pod)-1]["pod"]; snap.Cgroup != nil || snap.Pressure != nil || snap.Network != nil {
+		t.Errorf("%v has stats of merged containers", snap)
+	}
+}
+
+func TestHistorySubtree(t *testing.T) {
+	var history HistoryDB
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	entry := make(HistoryEntry)
+	for cgroup, p := range procs.GetCgroupsMap() {
+		entry[cgroup] = Snapshot{Timestamp: time.Now(), Processes: p}
+	}
+	history.Push(entry)
+	history.Push(entry)
+
+	// zombie sshd (8350) is collected along with its parent
+	containers := map[string]int{
+		"/docker":  8,
+		"/docker/": 8,
+		"/":        10,
+		"/user":    1,
+		"/dock":    0,
+		"/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a": 3,
+	}
+	for containerID, expected := range containers {
+		snap, err := history.Subtree(containerID, 2).GetLastData(containerID, 1, 1)
+		if expected == 0 {
+			if err == nil {
+				t.Errorf("GetLastData for empty subtree %s didn't failed when expected to fail", containerID)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal("getting subtree last data failed", err)
+		}
+		if len(snap.Processes) != expected {
+			t.Errorf("%d not equal to expected %d for %s", len(snap.Processes), expected, containerID)
+		}
+		// processes keep their exact leaf cgroups
+		for _, p := range snap.Processes {
+			if p.Cgroup == "" || p.Cgroup == "/docker" {
+				t.Errorf("%s is not a leaf cgroup of process %d", p.Cgroup, p.Status.Pid)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Kubernetes pod QoS classes
+const (
+	QOSGuaranteed = "Guaranteed"
+	QOSBurstable  = "Burstable"
+	QOSBestEffort = "BestEffort"
+)
+
+// KubePod identifies Kubernetes pod container by its cgroup
+type KubePod struct {
+	QOSClass    string `json:"qosclass"`
+	PodUID      string `json:"poduid"`
+	ContainerID string `json:"containerid,omitempty"`
+	// Namespace and Name are known only if kubelet pod directory
+	// is readable
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// kubePodSegment matches pod cgroup for both cgroupfs driver (pod{uid})
+// and systemd driver (kubepods-burstable-pod{uid_with_underscores}.slice)
+var kubePodSegment = regexp.MustCompile("^(?:kubepods-(?:(burstable|besteffort)-)?)?pod([0-9a-fA-F_-]+)(?:\\.slice)?$")
+
+// kubeContainerSegment matches container cgroup like {id}, docker-{id}.scope,
+// cri-containerd-{id}.scope or crio-{id}.scope, but not crio-conmon-{id}.scope
+// of container monitor process
+var kubeContainerSegment = regexp.MustCompile("^(?:docker-|cri-containerd-|crio-)?([0-9a-f]{64})(?:\\.scope)?$")
+
+// ParseKubeCgroup extracts QoS class, pod UID and container ID from
+// Kubernetes cgroup paths, created with either cgroupfs or systemd driver:
+//
+//	/kubepods/burstable/pod{uid}/{id}
+//	/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod{uid}.slice/cri-containerd-{id}.scope
+func ParseKubeCgroup(cgroup string) (*KubePod, bool) {
+	if !strings.HasPrefix(cgroup, "/kubepods") {
+		return nil, false
+	}
+	parts := strings.Split(strings.Trim(cgroup, "/"), "/")
+	for i, part := range parts {
+		m := kubePodSegment.FindStringSubmatch(part)
+		if m == nil {
+			continue
+		}
+		pod := KubePod{
+			QOSClass: QOSGuaranteed,
+			// systemd driver escapes dashes in pod UID
+			PodUID: strings.Replace(m[2], "_", "-", -1),
+		}
+		qos := m[1]
+		if qos == "" && i > 0 {
+			// cgroupfs driver keeps QoS class in parent directory
+			qos = strings.TrimSuffix(strings.TrimPrefix(parts[i-1], "kubepods-"), ".slice")
+		}
+		switch qos {
+		case "burstable":
+			pod.QOSClass = QOSBurstable
+		case "besteffort":
+			pod.QOSClass = QOSBestEffort
+		}
+		if i+1 < len(parts) {
+			if c := kubeContainerSegment.FindStringSubmatch(parts[i+1]); c != nil {
+				pod.ContainerID = c[1]
+			}
+		}
+		return &pod, true
+	}
+	return nil, false
+}
+
+// Resolve fills pod namespace and name from pod logs directory, which is
+// named {namespace}_{name}_{uid}, falling back to kubelet pod directory
+func (pod *KubePod) Resolve(rootPath string) {
+	dirs, _ := filepath.Glob(filepath.Join(rootPath, "/var/log/pods", "*_"+pod.PodUID))
+	for _, d := range dirs {
+		parts := strings.SplitN(filepath.Base(d), "_", 3)
+		if len(parts) == 3 {
+			pod.Namespace = parts[0]
+			pod.Name = parts[1]
+			return
+		}
+	}
+	podDir := filepath.Join(rootPath, "/var/lib/kubelet/pods", pod.PodUID)
+	// every service account token volume has namespace file
+	for _, volumes := range []string{"kubernetes.io~projected", "kubernetes.io~secret"} {
+		files, _ := filepath.Glob(filepath.Join(podDir, "volumes", volumes, "*", "namespace"))
+		for _, f := range files {
+			if dataBytes, err := ioutil.ReadFile(f); err == nil {
+				pod.Namespace = strings.TrimSpace(string(dataBytes))
+				break
+			}
+		}
+		if pod.Namespace != "" {
+			break
+		}
+	}
+	// kubelet-managed hosts file ends with pod IP and hostname, which is
+	// the pod name unless pod uses host network or sets hostname explicitly
+	if f, err := os.Open(filepath.Join(podDir, "etc-hosts")); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "# Entries added by HostAliases") {
+				break
+			}
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") {
+				pod.Name = fields[1]
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestParseKubeCgroup(t *testing.T) {
+	id := "7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad"
+	uid := "0b1f2c9e-4d6a-4c1b-9a8e-2f3d4c5b6a71"
+	systemdUID := "0b1f2c9e_4d6a_4c1b_9a8e_2f3d4c5b6a71"
+	cgroups := map[string]KubePod{
+		"/kubepods/burstable/pod" + uid + "/" + id:  {QOSClass: QOSBurstable, PodUID: uid, ContainerID: id},
+		"/kubepods/besteffort/pod" + uid + "/" + id: {QOSClass: QOSBestEffort, PodUID: uid, ContainerID: id},
+		"/kubepods/pod" + uid + "/" + id:            {QOSClass: QOSGuaranteed, PodUID: uid, ContainerID: id},
+		"/kubepods/burstable/pod" + uid:             {QOSClass: QOSBurstable, PodUID: uid},
+		"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdUID + ".slice/cri-containerd-" + id + ".scope": {
+			QOSClass: QOSBurstable, PodUID: uid, ContainerID: id},
+		"/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + systemdUID + ".slice/crio-" + id + ".scope": {
+			QOSClass: QOSBestEffort, PodUID: uid, ContainerID: id},
+		"/kubepods.slice/kubepods-pod" + systemdUID + ".slice/docker-" + id + ".scope": {
+			QOSClass: QOSGuaranteed, PodUID: uid, ContainerID: id},
+		"/kubepods/besteffort/pod" + uid + "/crio-" + id: {QOSClass: QOSBestEffort, PodUID: uid, ContainerID: id},
+		// container monitor process is not the container itself
+		"/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + systemdUID + ".slice/crio-conmon-" + id + ".scope": {
+			QOSClass: QOSBestEffort, PodUID: uid},
+	}
+	for cgroup, expected := range cgroups {
+		pod, ok := ParseKubeCgroup(cgroup)
+		if !ok {
+			t.Errorf("%s not parsed as Kubernetes cgroup", cgroup)
+			continue
+		}
+		if *pod != expected {
+			t.Errorf("%v not equal to expected %v for %s", *pod, expected, cgroup)
+		}
+	}
+}
+
+func TestParseKubeCgroupNonKube(t *testing.T) {
+	cgroups := []string{
+		"/",
+		"/kubepods",
+		"/kubepods.slice/kubepods-burstable.slice",
+		"/docker/7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad",
+		"/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope",
+	}
+	for _, cgroup := range cgroups {
+		if pod, ok := ParseKubeCgroup(cgroup); ok {
+			t.Errorf("%s parsed as Kubernetes cgroup %v", cgroup, pod)
+		}
+	}
+}
+
+func TestKubePodResolveKubelet(t *testing.T) {
+	pod := KubePod{PodUID: "0b1f2c9e-4d6a-4c1b-9a8e-2f3d4c5b6a71"}
+	pod.Resolve("./testroot")
+	expectedNamespace := "production"
+	if pod.Namespace != expectedNamespace {
+		t.Errorf("%s not equal to expected %s", pod.Namespace, expectedNamespace)
+	}
+	expectedName := "web-7f9c6d8b5-2mxkq"
+	if pod.Name != expectedName {
+		t.Errorf("%s not equal to expected %s", pod.Name, expectedName)
+	}
+}
+
+func TestKubePodResolveHostNetwork(t *testing.T) {
+	// hostNetwork pod gets node hosts file, so its name comes from logs
+	pod := KubePod{PodUID: "3c4d5e6f-7a8b-4c9d-8e0f-1a2b3c4d5e6f"}
+	pod.Resolve("./testroot")
+	expectedNamespace := "monitoring"
+	if pod.Namespace != expectedNamespace {
+		t.Errorf("%s not equal to expected %s", pod.Namespace, expectedNamespace)
+	}
+	expectedName := "node-exporter-q9w4z"
+	if pod.Name != expectedName {
+		t.Errorf("%s not equal to expected %s", pod.Name, expectedName)
+	}
+}
+
+func TestKubePodResolveLogs(t *testing.T) {
+	pod := KubePod{PodUID: "5e6f7a8b-1c2d-4e3f-8a9b-0c1d2e3f4a5b"}
+	pod.Resolve("./testroot")
+	expectedNamespace := "kube-system"
+	if pod.Namespace != expectedNamespace {
+		t.Errorf("%s not equal to expected %s", pod.Namespace, expectedNamespace)
+	}
+	expectedName := "coredns-5d78c9869d-qz4xw"
+	if pod.Name != expectedName {
+		t.Errorf("%s not equal to expected %s", pod.Name, expectedName)
+	}
+}
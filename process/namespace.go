@@ -0,0 +1,158 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// namespaceTypes are namespaces we read from /proc/{pid}/ns
+var namespaceTypes = []string{"cgroup", "ipc", "mnt", "net", "pid", "user", "uts"}
+
+// SharedNamespaceTypes are namespaces we group containers by
+var SharedNamespaceTypes = []string{"pid", "net", "ipc"}
+
+// NamespaceGroup lists containers sharing the same namespace
+type NamespaceGroup struct {
+	Type       string   `json:"type"`
+	Inode      uint64   `json:"inode"`
+	Containers []string `json:"containers"`
+}
+
+// ReadNamespace returns inode of process namespace,
+// path is /proc/{pid}, name is namespace type like "net"
+func ReadNamespace(path, name string) (uint64, error) {
+	link, err := os.Readlink(filepath.Join(path, "ns", name))
+	if err != nil {
+		return 0, err
+	}
+	// link looks like net:[4026531993]
+	prefix := name + ":["
+	if !strings.HasPrefix(link, prefix) || !strings.HasSuffix(link, "]") {
+		return 0, fmt.Errorf("Wrong namespace link %s", link)
+	}
+	return strconv.ParseUint(link[len(prefix):len(link)-1], 10, 64)
+}
+
+// ReadNamespaces returns inodes of all readable process namespaces
+// by their type, path is /proc/{pid}
+func ReadNamespaces(path string) map[string]uint64 {
+	var result map[string]uint64
+	for _, name := range namespaceTypes {
+		inode, err := ReadNamespace(path, name)
+		if err != nil {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]uint64)
+		}
+		result[name] = inode
+	}
+	return result
+}
+
+// ReadNSpid returns process PIDs in all its nested PID namespaces,
+// from the host one to the innermost one, from /proc/{pid}/status file
+func ReadNSpid(path string) ([]uint64, error) {
+	value, err := readStatusField(path, "NSpid")
+	if err != nil {
+		return nil, err
+	}
+	var result []uint64
+	for _, f := range strings.Fields(value) {
+		pid, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, pid)
+	}
+	return result, nil
+}
+
+// readStatusField returns value of /proc/{pid}/status field
+// which linuxproc.ProcessStatus doesn't know about
+func readStatusField(path, key string) (string, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	for _, l := range strings.Split(string(dataBytes), "\n") {
+		if strings.HasPrefix(l, key+":") {
+			return strings.TrimSpace(strings.TrimPrefix(l, key+":")), nil
+		}
+	}
+	// fields are added in newer kernels, like NSpid in 4.1
+	return "", fmt.Errorf("No %s in %s", key, path)
+}
+
+// FindContainerPid searches for process with given PID as it
+// is seen inside the container, and returns it if found
+func (procs List) FindContainerPid(pid uint64) *Process {
+	for _, p := range procs {
+		if p.ContainerPid == pid {
+			return &p
+		}
+	}
+	return nil
+}
+
+// SharedNamespaces returns groups of containers sharing the same
+// namespace of `types`, in order of `types` and by inode
+func (entry HistoryEntry) SharedNamespaces(types []string) []NamespaceGroup {
+	members := make(map[string]map[uint64]map[string]bool)
+	for _, t := range types {
+		members[t] = make(map[uint64]map[string]bool)
+	}
+	for name, snap := range entry {
+		for _, p := range snap.Processes {
+			for t, inode := range p.Namespaces {
+				if members[t] == nil {
+					continue
+				}
+				if members[t][inode] == nil {
+					members[t][inode] = make(map[string]bool)
+				}
+				members[t][inode][name] = true
+			}
+		}
+	}
+	result := make([]NamespaceGroup, 0)
+	for _, t := range types {
+		var groups []NamespaceGroup
+		for inode, containers := range members[t] {
+			if len(containers) < 2 {
+				continue
+			}
+			g := NamespaceGroup{Type: t, Inode: inode}
+			for name := range containers {
+				g.Containers = append(g.Containers, name)
+			}
+			sort.Strings(g.Containers)
+			groups = append(groups, g)
+		}
+		sort.Sort(byInode(groups))
+		result = append(result, groups...)
+	}
+	return result
+}
+
+// byInode helps us sort namespace groups of the same type
+type byInode []NamespaceGroup
+
+func (g byInode) Len() int {
+	return len(g)
+}
+func (g byInode) Swap(i, j int) {
+	g[i], g[j] = g[j], g[i]
+}
+func (g byInode) Less(i, j int) bool {
+	return g[i].Inode < g[j].Inode
+}
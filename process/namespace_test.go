@@ -0,0 +1,91 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadNamespace(t *testing.T) {
+	inode, err := ReadNamespace("./testroot/proc/8743", "net")
+	if err != nil {
+		t.Fatal("namespace read fail", err)
+	}
+	var expected uint64 = 4026532250
+	if inode != expected {
+		t.Errorf("%v not equal to expected %v", inode, expected)
+	}
+	if _, err := ReadNamespace("./testroot/proc/8743", "user"); err == nil {
+		t.Error("ReadNamespace for missing namespace didn't failed when expected to fail")
+	}
+}
+
+func TestReadNamespaces(t *testing.T) {
+	namespaces := ReadNamespaces("./testroot/proc/22291")
+	expected := map[string]uint64{"ipc": 4026532247, "net": 4026532250, "pid": 4026532300}
+	if !reflect.DeepEqual(namespaces, expected) {
+		t.Errorf("%v not equal to expected %v", namespaces, expected)
+	}
+	if namespaces := ReadNamespaces("./testroot/proc/2309"); namespaces != nil {
+		t.Errorf("%v not equal to expected %v", namespaces, nil)
+	}
+}
+
+func TestReadNSpid(t *testing.T) {
+	nspid, err := ReadNSpid("./testroot/proc/8743/status")
+	if err != nil {
+		t.Fatal("NSpid read fail", err)
+	}
+	expected := []uint64{8743, 12}
+	if !reflect.DeepEqual(nspid, expected) {
+		t.Errorf("%v not equal to expected %v", nspid, expected)
+	}
+	if _, err := ReadNSpid("./testroot/proc/2309/status"); err == nil {
+		t.Error("ReadNSpid for status without NSpid didn't failed when expected to fail")
+	}
+}
+
+func TestFindContainerPid(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	p := procs.FindContainerPid(12)
+	if p == nil {
+		t.Fatal("process with container PID 12 not found")
+	}
+	var expected uint64 = 8743
+	if p.Status.Pid != expected {
+		t.Errorf("%v not equal to expected %v", p.Status.Pid, expected)
+	}
+	if p := procs.FindContainerPid(100); p != nil {
+		t.Errorf("%v not equal to expected %v", p, nil)
+	}
+}
+
+func TestSharedNamespaces(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	entry := make(HistoryEntry)
+	for name, p := range procs.GetCgroupsMap() {
+		entry[name] = Snapshot{Timestamp: time.Now(), Processes: p}
+	}
+	containers := []string{
+		"/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a",
+		"/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope",
+	}
+	expected := []NamespaceGroup{
+		{Type: "net", Inode: 4026532250, Containers: containers},
+		{Type: "ipc", Inode: 4026532247, Containers: containers},
+	}
+	groups := entry.SharedNamespaces(SharedNamespaceTypes)
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("%v not equal to expected %v", groups, expected)
+	}
+}
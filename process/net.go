@@ -0,0 +1,240 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Socket is a single socket from /proc/{pid}/net files
+type Socket struct {
+	Protocol      string `json:"protocol"`
+	LocalAddress  string `json:"localaddress"`
+	RemoteAddress string `json:"remoteaddress,omitempty"`
+	State         string `json:"state"`
+	Inode         uint64 `json:"inode"`
+	// Pids are processes holding this socket open
+	Pids []uint64 `json:"pids,omitempty"`
+	// Namespace is inode of network namespace socket belongs to
+	Namespace uint64 `json:"netns,omitempty"`
+}
+
+// Connections holds sockets of container network namespace
+type Connections struct {
+	Listening   []Socket `json:"listening"`
+	Established []Socket `json:"established"`
+	// States counts sockets by protocol and state
+	States map[string]map[string]int `json:"states"`
+}
+
+// tcpStates maps /proc/net/tcp st field to state name
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// unixAcceptCon is __SO_ACCEPTCON flag of listening unix sockets
+const unixAcceptCon = 0x10000
+
+// parseSocketAddress parses hex address:port from /proc/net/tcp,
+// where address is stored as 32-bit words in host (little-endian) order
+func parseSocketAddress(s string) (string, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("Wrong socket address %s", s)
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", fmt.Errorf("Wrong socket address %s", s)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ip.String(), strconv.FormatUint(port, 10)), nil
+}
+
+// ReadInetSockets parses /proc/{pid}/net/{tcp,tcp6,udp,udp6} file
+func ReadInetSockets(path, protocol string) ([]Socket, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []Socket
+	lines := strings.Split(string(dataBytes), "\n")
+	// skip header
+	for _, l := range lines[1:] {
+		fields := strings.Fields(l)
+		if len(fields) < 10 {
+			continue
+		}
+		s := Socket{Protocol: protocol}
+		if s.LocalAddress, err = parseSocketAddress(fields[1]); err != nil {
+			continue
+		}
+		if s.RemoteAddress, err = parseSocketAddress(fields[2]); err != nil {
+			continue
+		}
+		s.State = tcpStates[fields[3]]
+		if strings.HasPrefix(protocol, "udp") {
+			// unconnected UDP sockets are in CLOSE state and just listen
+			s.State = "UNCONN"
+			if fields[3] == "01" {
+				s.State = "ESTABLISHED"
+			}
+		}
+		s.Inode, _ = strconv.ParseUint(fields[9], 10, 64)
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// ReadUnixSockets parses /proc/{pid}/net/unix file
+func ReadUnixSockets(path string) ([]Socket, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []Socket
+	lines := strings.Split(string(dataBytes), "\n")
+	// skip header
+	for _, l := range lines[1:] {
+		fields := strings.Fields(l)
+		if len(fields) < 7 {
+			continue
+		}
+		s := Socket{Protocol: "unix"}
+		flags, _ := strconv.ParseUint(fields[3], 16, 64)
+		switch {
+		case flags&unixAcceptCon != 0:
+			s.State = "LISTEN"
+		case fields[5] == "03":
+			s.State = "ESTABLISHED"
+		default:
+			s.State = "UNCONN"
+		}
+		s.Inode, _ = strconv.ParseUint(fields[6], 10, 64)
+		if len(fields) > 7 {
+			s.LocalAddress = fields[7]
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// ReadSocketInodes returns inodes of sockets process holds open,
+// path is /proc/{pid}
+func ReadSocketInodes(path string) ([]uint64, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(path, "fd"))
+	if err != nil {
+		return nil, err
+	}
+	var result []uint64
+	for _, fi := range fis {
+		link, err := os.Readlink(filepath.Join(path, "fd", fi.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+		if err == nil {
+			result = append(result, inode)
+		}
+	}
+	return result, nil
+}
+
+// GetConnections returns sockets of network namespaces of given processes.
+// Processes may come from several namespaces with recursive and pod queries,
+// so sockets are read once per namespace, for the first process we can
+// read them for, and are mapped back to processes through their fd links
+func GetConnections(rootPath string, procs List) (*Connections, error) {
+	var sockets []Socket
+	// namespace is 0 for processes with unreadable ns links
+	done := make(map[uint64]bool)
+	for _, p := range procs {
+		ns := p.Namespaces["net"]
+		if done[ns] {
+			continue
+		}
+		netPath := filepath.Join(rootPath, "/proc", strconv.FormatUint(p.Status.Pid, 10), "net")
+		var nsSockets []Socket
+		found := false
+		for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+			s, err := ReadInetSockets(filepath.Join(netPath, protocol), protocol)
+			if err == nil {
+				found = true
+				nsSockets = append(nsSockets, s...)
+			}
+		}
+		if s, err := ReadUnixSockets(filepath.Join(netPath, "unix")); err == nil {
+			found = true
+			nsSockets = append(nsSockets, s...)
+		}
+		if !found {
+			continue
+		}
+		done[ns] = true
+		for i := range nsSockets {
+			nsSockets[i].Namespace = ns
+		}
+		sockets = append(sockets, nsSockets...)
+	}
+	if len(done) == 0 {
+		return nil, errors.New("No readable network information for processes")
+	}
+
+	owners := make(map[uint64][]uint64)
+	for _, p := range procs {
+		inodes, _ := ReadSocketInodes(filepath.Join(rootPath, "/proc", strconv.FormatUint(p.Status.Pid, 10)))
+		for _, inode := range inodes {
+			owners[inode] = append(owners[inode], p.Status.Pid)
+		}
+	}
+
+	conns := Connections{
+		Listening:   make([]Socket, 0),
+		Established: make([]Socket, 0),
+		States:      make(map[string]map[string]int),
+	}
+	for _, s := range sockets {
+		// inode 0 means socket is not owned by anyone, like TIME_WAIT ones
+		if s.Inode != 0 {
+			s.Pids = owners[s.Inode]
+		}
+		if conns.States[s.Protocol] == nil {
+			conns.States[s.Protocol] = make(map[string]int)
+		}
+		conns.States[s.Protocol][s.State]++
+		switch {
+		case s.State == "LISTEN",
+			s.State == "UNCONN" && s.Protocol != "unix":
+			conns.Listening = append(conns.Listening, s)
+		case s.State == "ESTABLISHED":
+			conns.Established = append(conns.Established, s)
+		}
+	}
+	return &conns, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSocketAddress(t *testing.T) {
+	addresses := map[string]string{
+		"050011AC:1355":                         "172.17.0.5:4949",
+		"00000000:0202":                         "0.0.0.0:514",
+		"00000000000000000000000001000000:1F90": "[::1]:8080",
+	}
+	for raw, expected := range addresses {
+		address, err := parseSocketAddress(raw)
+		if err != nil {
+			t.Fatal("socket address parse fail", err)
+		}
+		if address != expected {
+			t.Errorf("%v not equal to expected %v", address, expected)
+		}
+	}
+	if _, err := parseSocketAddress("0500:11AC:1355"); err == nil {
+		t.Error("parseSocketAddress with wrong address didn't failed when expected to fail")
+	}
+}
+
+func TestReadInetSockets(t *testing.T) {
+	sockets, err := ReadInetSockets("./testroot/proc/8743/net/tcp", "tcp")
+	if err != nil {
+		t.Fatal("sockets read fail", err)
+	}
+	expected := []Socket{
+		{Protocol: "tcp", LocalAddress: "0.0.0.0:4949", RemoteAddress: "0.0.0.0:0", State: "LISTEN", Inode: 2394815},
+		{Protocol: "tcp", LocalAddress: "172.17.0.5:4949", RemoteAddress: "172.17.42.1:53422", State: "ESTABLISHED", Inode: 2394899},
+		{Protocol: "tcp", LocalAddress: "172.17.0.5:4949", RemoteAddress: "172.17.42.1:53408", State: "TIME_WAIT", Inode: 0},
+	}
+	if !reflect.DeepEqual(sockets, expected) {
+		t.Errorf("%v not equal to expected %v", sockets, expected)
+	}
+}
+
+func TestReadUnixSockets(t *testing.T) {
+	sockets, err := ReadUnixSockets("./testroot/proc/8743/net/unix")
+	if err != nil {
+		t.Fatal("sockets read fail", err)
+	}
+	expectedStates := []string{"UNCONN", "LISTEN", "ESTABLISHED"}
+	if len(sockets) != len(expectedStates) {
+		t.Fatalf("%d not equal to expected %d", len(sockets), len(expectedStates))
+	}
+	for i, s := range sockets {
+		if s.State != expectedStates[i] {
+			t.Errorf("%v not equal to expected %v", s.State, expectedStates[i])
+		}
+	}
+	if sockets[0].LocalAddress != "/dev/log" {
+		t.Errorf("%v not equal to expected %v", sockets[0].LocalAddress, "/dev/log")
+	}
+}
+
+func TestGetConnections(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	procs = procs.GetCgroupsMap()["/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"]
+	conns, err := GetConnections("./testroot", procs)
+	if err != nil {
+		t.Fatal("connections read fail", err)
+	}
+	expectedListening := map[string][]uint64{
+		"0.0.0.0:4949":          {8743},
+		"[::]:514":              nil,
+		"0.0.0.0:514":           nil,
+		"/var/run/rsyslogd.ctl": nil,
+	}
+	if len(conns.Listening) != len(expectedListening) {
+		t.Fatalf("%v not equal to expected %v", conns.Listening, expectedListening)
+	}
+	for _, s := range conns.Listening {
+		pids, ok := expectedListening[s.LocalAddress]
+		if !ok || !reflect.DeepEqual(s.Pids, pids) {
+			t.Errorf("%v %v not equal to expected %v", s.LocalAddress, s.Pids, pids)
+		}
+	}
+	if len(conns.Established) != 3 {
+		t.Errorf("%d not equal to expected %d", len(conns.Established), 3)
+	}
+	if conns.States["tcp"]["TIME_WAIT"] != 1 {
+		t.Errorf("%d not equal to expected %d", conns.States["tcp"]["TIME_WAIT"], 1)
+	}
+}
+
+func TestGetConnectionsNamespaces(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	p := procs.FindProc(8743)
+	if p == nil {
+		t.Fatal("process 8743 not found")
+	}
+	// same process seen in two namespaces gives us two sets of sockets
+	other := *p
+	other.Namespaces = map[string]uint64{"net": 4026532999}
+	single, err := GetConnections("./testroot", List{*p, *p})
+	if err != nil {
+		t.Fatal("connections read fail", err)
+	}
+	double, err := GetConnections("./testroot", List{*p, other})
+	if err != nil {
+		t.Fatal("connections read fail", err)
+	}
+	if len(double.Listening) != 2*len(single.Listening) {
+		t.Errorf("%d not equal to expected %d", len(double.Listening), 2*len(single.Listening))
+	}
+	namespaces := make(map[uint64]bool)
+	for _, s := range double.Listening {
+		namespaces[s.Namespace] = true
+	}
+	if !namespaces[4026532250] || !namespaces[4026532999] {
+		t.Errorf("%v are not expected namespaces", namespaces)
+	}
+}
+
+func TestGetConnectionsMissing(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	procs = procs.GetCgroupsMap()["/docker/020361380fe7e4abf7117201cd2936a9cbbac757a78365651c38294eabe43ef0"]
+	if _, err := GetConnections("./testroot", procs); err == nil {
+		t.Error("GetConnections for processes without net dir didn't failed when expected to fail")
+	}
+}
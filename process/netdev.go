@@ -0,0 +1,195 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InterfaceStats holds network interface counters from /proc/{pid}/net/dev
+type InterfaceStats struct {
+	Name      string `json:"name"`
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+	// Rates are per second since the previous sample
+	RxBytesRate   float64 `json:"rx_bytes_rate"`
+	RxPacketsRate float64 `json:"rx_packets_rate"`
+	RxErrorsRate  float64 `json:"rx_errors_rate"`
+	TxBytesRate   float64 `json:"tx_bytes_rate"`
+	TxPacketsRate float64 `json:"tx_packets_rate"`
+	TxErrorsRate  float64 `json:"tx_errors_rate"`
+}
+
+// NetworkStats holds interfaces of container network namespace
+type NetworkStats struct {
+	// Namespace is inode of network namespace
+	Namespace  uint64           `json:"namespace"`
+	Interfaces []InterfaceStats `json:"interfaces"`
+	// Shared lists other containers in the same network namespace
+	Shared []string `json:"shared,omitempty"`
+}
+
+// NetworkSample is NetworkStats of container in time
+type NetworkSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	*NetworkStats
+}
+
+// ReadNetDev parses /proc/{pid}/net/dev file
+func ReadNetDev(path string) ([]InterfaceStats, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]InterfaceStats, 0)
+	for _, l := range strings.Split(string(dataBytes), "\n") {
+		parts := strings.SplitN(l, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 16 {
+			continue
+		}
+		var counters [16]uint64
+		for i := range counters {
+			if counters[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, InterfaceStats{
+			Name:      strings.TrimSpace(parts[0]),
+			RxBytes:   counters[0],
+			RxPackets: counters[1],
+			RxErrors:  counters[2],
+			TxBytes:   counters[8],
+			TxPackets: counters[9],
+			TxErrors:  counters[10],
+		})
+	}
+	return result, nil
+}
+
+// rate returns per second increase of counter,
+// counters are reset when namespace is recreated, so we skip such samples
+func rate(cur, prev uint64, seconds float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+// SetRates calculates per second rates since `prev` sample taken
+// `seconds` ago, interfaces are matched by name
+func (stats *NetworkStats) SetRates(prev *NetworkStats, seconds float64) {
+	if stats == nil || prev == nil || seconds <= 0 || stats.Namespace != prev.Namespace {
+		return
+	}
+	for i := range stats.Interfaces {
+		cur := &stats.Interfaces[i]
+		for _, p := range prev.Interfaces {
+			if p.Name != cur.Name {
+				continue
+			}
+			cur.RxBytesRate = rate(cur.RxBytes, p.RxBytes, seconds)
+			cur.RxPacketsRate = rate(cur.RxPackets, p.RxPackets, seconds)
+			cur.RxErrorsRate = rate(cur.RxErrors, p.RxErrors, seconds)
+			cur.TxBytesRate = rate(cur.TxBytes, p.TxBytes, seconds)
+			cur.TxPacketsRate = rate(cur.TxPackets, p.TxPackets, seconds)
+			cur.TxErrorsRate = rate(cur.TxErrors, p.TxErrors, seconds)
+		}
+	}
+}
+
+// GetNetworks reads network interfaces for every container in cgroups map.
+// All container processes share network namespace, so only one
+// representative process is read per container, and every namespace
+// is read only once, even if several containers share it
+func GetNetworks(rootPath string, cgroups map[string]List) map[string]*NetworkStats {
+	// iterate in stable order, so shared namespaces are read the same way
+	names := make([]string, 0, len(cgroups))
+	for name := range cgroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	namespaces := make(map[uint64]*NetworkStats)
+	members := make(map[uint64][]string)
+	for _, name := range names {
+		inode, stats, err := readNetwork(rootPath, cgroups[name], namespaces)
+		if err != nil {
+			continue
+		}
+		namespaces[inode] = stats
+		members[inode] = append(members[inode], name)
+	}
+
+	result := make(map[string]*NetworkStats)
+	for inode, names := range members {
+		for _, name := range names {
+			// every container gets its own copy, as rates
+			// are calculated against its own history
+			stats := *namespaces[inode]
+			stats.Interfaces = append([]InterfaceStats(nil), stats.Interfaces...)
+			stats.Shared = nil
+			for _, other := range names {
+				if other != name {
+					stats.Shared = append(stats.Shared, other)
+				}
+			}
+			result[name] = &stats
+		}
+	}
+	return result
+}
+
+// readNetwork finds representative process and reads its network
+// namespace, unless it is already in `known` namespaces
+func readNetwork(rootPath string, procs List, known map[uint64]*NetworkStats) (uint64, *NetworkStats, error) {
+	for _, p := range procs {
+		path := filepath.Join(rootPath, "/proc", strconv.FormatUint(p.Status.Pid, 10))
+		inode, err := ReadNamespace(path, "net")
+		if err != nil {
+			continue
+		}
+		if stats, ok := known[inode]; ok {
+			return inode, stats, nil
+		}
+		interfaces, err := ReadNetDev(filepath.Join(path, "net", "dev"))
+		if err != nil {
+			continue
+		}
+		return inode, &NetworkStats{Namespace: inode, Interfaces: interfaces}, nil
+	}
+	return 0, nil, errors.New("No readable network namespace for processes")
+}
+
+// GetNetwork returns network stats of container
+// for every history entry it is present in, oldest first
+func (history *HistoryDB) GetNetwork(containerID string) ([]NetworkSample, error) {
+	result := make([]NetworkSample, 0)
+	for _, entry := range history {
+		snap, ok := entry[containerID]
+		if !ok || snap.Network == nil {
+			continue
+		}
+		result = append(result, NetworkSample{Timestamp: snap.Timestamp, NetworkStats: snap.Network})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("Network of container %s not found", containerID)
+	}
+	return result, nil
+}
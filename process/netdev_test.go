@@ -0,0 +1,98 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadNetDev(t *testing.T) {
+	interfaces, err := ReadNetDev("./testroot/proc/8743/net/dev")
+	if err != nil {
+		t.Fatal("net/dev read fail", err)
+	}
+	expected := []InterfaceStats{
+		{Name: "lo", RxBytes: 648, RxPackets: 8, TxBytes: 648, TxPackets: 8},
+		{Name: "eth0", RxBytes: 1296345, RxPackets: 10482, RxErrors: 2, TxBytes: 5483410, TxPackets: 9321},
+	}
+	if !reflect.DeepEqual(interfaces, expected) {
+		t.Errorf("%v not equal to expected %v", interfaces, expected)
+	}
+}
+
+func TestSetRates(t *testing.T) {
+	prev := &NetworkStats{Namespace: 1, Interfaces: []InterfaceStats{
+		{Name: "eth0", RxBytes: 1000, TxBytes: 500, RxPackets: 10},
+	}}
+	cur := &NetworkStats{Namespace: 1, Interfaces: []InterfaceStats{
+		{Name: "eth0", RxBytes: 3000, TxBytes: 400, RxPackets: 30},
+		{Name: "eth1", RxBytes: 3000},
+	}}
+	cur.SetRates(prev, 2)
+	eth0 := cur.Interfaces[0]
+	if eth0.RxBytesRate != 1000 || eth0.RxPacketsRate != 10 {
+		t.Errorf("%v/%v not equal to expected %v/%v", eth0.RxBytesRate, eth0.RxPacketsRate, 1000, 10)
+	}
+	// counter reset
+	if eth0.TxBytesRate != 0 {
+		t.Errorf("%v not equal to expected %v", eth0.TxBytesRate, 0)
+	}
+	if cur.Interfaces[1].RxBytesRate != 0 {
+		t.Errorf("%v not equal to expected %v", cur.Interfaces[1].RxBytesRate, 0)
+	}
+}
+
+func TestGetNetworks(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	networks := GetNetworks("./testroot", procs.GetCgroupsMap())
+	docker := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	systemd := "/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope"
+	if len(networks) != 2 {
+		t.Fatalf("%d not equal to expected %d", len(networks), 2)
+	}
+	for name, other := range map[string]string{docker: systemd, systemd: docker} {
+		stats := networks[name]
+		if stats == nil {
+			t.Fatalf("no network for %s", name)
+		}
+		if len(stats.Interfaces) != 2 {
+			t.Errorf("%d not equal to expected %d", len(stats.Interfaces), 2)
+		}
+		if !reflect.DeepEqual(stats.Shared, []string{other}) {
+			t.Errorf("%v not equal to expected %v", stats.Shared, []string{other})
+		}
+	}
+	if &networks[docker].Interfaces[0] == &networks[systemd].Interfaces[0] {
+		t.Error("containers sharing namespace share interfaces slice")
+	}
+}
+
+func TestGetNetwork(t *testing.T) {
+	var history HistoryDB
+	containerID := "/docker/test"
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		entry := HistoryEntry{containerID: Snapshot{
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+			Network:   &NetworkStats{Namespace: 1},
+		}}
+		history.Push(entry)
+	}
+	samples, err := history.GetNetwork(containerID)
+	if err != nil {
+		t.Fatal("network history fail", err)
+	}
+	if len(samples) != 3 {
+		t.Errorf("%d not equal to expected %d", len(samples), 3)
+	}
+	if _, err := history.GetNetwork("/docker/missing"); err == nil {
+		t.Error("GetNetwork for missing container didn't failed when expected to fail")
+	}
+}
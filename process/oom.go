@@ -0,0 +1,121 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxOOMEvents is how many OOM events OOMLog keeps
+const MaxOOMEvents = 100
+
+// OOMVictim is the process which most likely was killed by OOM killer
+type OOMVictim struct {
+	Pid     uint64 `json:"pid"`
+	Cmdline string `json:"cmdline"`
+	// VmRSS is the last known RSS of the process, in kB
+	VmRSS uint64 `json:"vmrss"`
+}
+
+// OOMEvent records OOM kills in container between two samples
+type OOMEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Container string    `json:"container"`
+	// Kills is the number of OOM kills since previous sample
+	Kills uint64 `json:"kills"`
+	// Victim is the largest process that vanished since previous sample,
+	// it's missing if no process vanished
+	Victim *OOMVictim `json:"victim,omitempty"`
+}
+
+// OOMLog keeps last MaxOOMEvents OOM events, oldest first
+type OOMLog []OOMEvent
+
+// DetectOOM checks if container oom_kill counter grew between two
+// consecutive snapshots, and returns OOM event with the likely victim
+func DetectOOM(containerID string, prev, cur Snapshot) *OOMEvent {
+	if prev.Cgroup == nil || cur.Cgroup == nil {
+		return nil
+	}
+	// counter is reset when container restarts
+	if cur.Cgroup.OOMKill <= prev.Cgroup.OOMKill {
+		return nil
+	}
+	event := OOMEvent{
+		Timestamp: cur.Timestamp,
+		Container: containerID,
+		Kills:     cur.Cgroup.OOMKill - prev.Cgroup.OOMKill,
+	}
+	for _, p := range prev.Processes {
+		if c := cur.Processes.FindProc(p.Status.Pid); c != nil && c.Stat.Starttime == p.Stat.Starttime {
+			continue
+		}
+		if event.Victim == nil || p.Status.VmRSS > event.Victim.VmRSS {
+			event.Victim = &OOMVictim{
+				Pid:     p.Status.Pid,
+				Cmdline: p.Cmdline,
+				VmRSS:   p.Status.VmRSS,
+			}
+		}
+	}
+	return &event
+}
+
+// DetectVanishedOOM checks containers of `prev` entry which have no
+// processes in `cur` entry, as OOM killer often takes the only process
+// of container. Their cgroups are read while they still exist
+func DetectVanishedOOM(rootPath string, prev, cur HistoryEntry, timestamp time.Time) []OOMEvent {
+	var containers []string
+	for containerID := range prev {
+		if _, ok := cur[containerID]; !ok && containerID != HostContainer {
+			containers = append(containers, containerID)
+		}
+	}
+	sort.Strings(containers)
+	var result []OOMEvent
+	for _, containerID := range containers {
+		stats, err := ReadCgroupStats(rootPath, containerID)
+		if err != nil {
+			continue
+		}
+		snap := Snapshot{Timestamp: timestamp, Cgroup: stats}
+		if oom := DetectOOM(containerID, prev[containerID], snap); oom != nil {
+			result = append(result, *oom)
+		}
+	}
+	return result
+}
+
+// Push adds new event to log, dropping the oldest ones
+func (log *OOMLog) Push(event OOMEvent) {
+	*log = append(*log, event)
+	if len(*log) > MaxOOMEvents {
+		*log = (*log)[len(*log)-MaxOOMEvents:]
+	}
+}
+
+// ForContainer returns OOM events of given container, oldest first
+func (log OOMLog) ForContainer(containerID string) []OOMEvent {
+	result := make([]OOMEvent, 0)
+	for _, e := range log {
+		if e.Container == containerID {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// ForSubtree returns OOM events of given container and all its
+// descendant cgroups, oldest first
+func (log OOMLog) ForSubtree(containerID string) []OOMEvent {
+	result := make([]OOMEvent, 0)
+	for _, e := range log {
+		if InSubtree(containerID, e.Container) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func prepareOOMSnapshots() (Snapshot, Snapshot, error) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		return Snapshot{}, Snapshot{}, err
+	}
+	procs = procs.GetCgroupsMap()["/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"]
+	prev := Snapshot{Timestamp: time.Now(), Processes: procs, Cgroup: &CgroupStats{OOMKill: 1}}
+	// rsyslogd (8743) is the largest process, munin-node (8713) is smaller
+	var survivors List
+	for _, p := range procs {
+		if p.Status.Pid != 8743 && p.Status.Pid != 8713 {
+			survivors = append(survivors, p)
+		}
+	}
+	cur := Snapshot{Timestamp: time.Now(), Processes: survivors, Cgroup: &CgroupStats{OOMKill: 3}}
+	return prev, cur, nil
+}
+
+func TestDetectOOM(t *testing.T) {
+	prev, cur, err := prepareOOMSnapshots()
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	event := DetectOOM("test", prev, cur)
+	if event == nil {
+		t.Fatal("OOM kill not detected")
+	}
+	expectedKills := uint64(2)
+	if event.Kills != expectedKills {
+		t.Errorf("%d not equal to expected %d", event.Kills, expectedKills)
+	}
+	expectedVictim := OOMVictim{Pid: 8743, Cmdline: "/usr/sbin/rsyslogd -i /var/run/rsyslogd.pid -n", VmRSS: 35008}
+	if event.Victim == nil || *event.Victim != expectedVictim {
+		t.Errorf("%v not equal to expected %v", event.Victim, expectedVictim)
+	}
+}
+
+func TestDetectOOMNoKills(t *testing.T) {
+	prev, cur, err := prepareOOMSnapshots()
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	cur.Cgroup.OOMKill = prev.Cgroup.OOMKill
+	if event := DetectOOM("test", prev, cur); event != nil {
+		t.Errorf("%v detected when counter didn't change", event)
+	}
+	cur.Cgroup = nil
+	if event := DetectOOM("test", prev, cur); event != nil {
+		t.Errorf("%v detected without cgroup stats", event)
+	}
+}
+
+func TestOOMLog(t *testing.T) {
+	var log OOMLog
+	for i := 0; i < MaxOOMEvents+10; i++ {
+		log.Push(OOMEvent{Container: "first", Kills: uint64(i)})
+	}
+	log.Push(OOMEvent{Container: "second"})
+	if len(log) != MaxOOMEvents {
+		t.Errorf("%d not equal to expected %d", len(log), MaxOOMEvents)
+	}
+	expectedKills := uint64(11)
+	if log[0].Kills != expectedKills {
+		t.Errorf("%d not equal to expected %d", log[0].Kills, expectedKills)
+	}
+	expectedLen := 1
+	if len(log.ForContainer("second")) != expectedLen {
+		t.Errorf("%d not equal to expected %d", len(log.ForContainer("second")), expectedLen)
+	}
+}
+
+func TestDetectVanishedOOM(t *testing.T) {
+	prev, _, err := prepareOOMSnapshots()
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	// testroot cgroup has oom_kill 1
+	containerID := "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"
+	prev.Cgroup.OOMKill = 0
+	prevEntry := HistoryEntry{containerID: prev, "/docker/missing": prev}
+	events := DetectVanishedOOM("./testroot", prevEntry, HistoryEntry{}, time.Now())
+	if len(events) != 1 {
+		t.Fatalf("%d events detected, expected 1", len(events))
+	}
+	if events[0].Container != containerID || events[0].Kills != 1 {
+		t.Errorf("%v is not expected OOM event", events[0])
+	}
+	expectedPid := uint64(8743)
+	if events[0].Victim == nil || events[0].Victim.Pid != expectedPid {
+		t.Errorf("%v is not expected victim %d", events[0].Victim, expectedPid)
+	}
+	// containers still having processes are checked by DetectOOM
+	if events := DetectVanishedOOM("./testroot", prevEntry, prevEntry, time.Now()); len(events) != 0 {
+		t.Errorf("%v detected for present containers", events)
+	}
+}
+
+func TestOOMLogForSubtree(t *testing.T) {
+	var log OOMLog
+	log.Push(OOMEvent{Container: "/docker/one"})
+	log.Push(OOMEvent{Container: "/docker"})
+	log.Push(OOMEvent{Container: "/dockerd"})
+	if events := log.ForSubtree("/docker"); len(events) != 2 {
+		t.Errorf("%v is not expected subtree events", events)
+	}
+}
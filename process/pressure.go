@@ -0,0 +1,128 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PressureLine is a "some" or "full" line of Pressure Stall Information file
+type PressureLine struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	// Total is total stall time, in microseconds
+	Total uint64 `json:"total"`
+	// TotalDelta is stall time since the previous sample, in microseconds
+	TotalDelta uint64 `json:"total_delta"`
+}
+
+// Pressure is Pressure Stall Information for a single resource
+type Pressure struct {
+	Some PressureLine `json:"some"`
+	// Full is missing for CPU on kernels before 5.13
+	Full *PressureLine `json:"full,omitempty"`
+}
+
+// PressureStats holds cgroup CPU, memory and IO pressure
+type PressureStats struct {
+	CPU    *Pressure `json:"cpu,omitempty"`
+	Memory *Pressure `json:"memory,omitempty"`
+	IO     *Pressure `json:"io,omitempty"`
+}
+
+// ReadPressureFile parses PSI file like cpu.pressure
+func ReadPressureFile(path string) (*Pressure, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Pressure
+	found := false
+	for _, l := range strings.Split(string(dataBytes), "\n") {
+		fields := strings.Fields(l)
+		if len(fields) == 0 {
+			continue
+		}
+		var line PressureLine
+		for _, f := range fields[1:] {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "avg10":
+				line.Avg10, _ = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				line.Avg60, _ = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				line.Avg300, _ = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				line.Total, _ = strconv.ParseUint(kv[1], 10, 64)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			p.Some = line
+			found = true
+		case "full":
+			p.Full = &line
+		}
+	}
+	if !found {
+		return nil, errors.New("No pressure data in " + path)
+	}
+	return &p, nil
+}
+
+// ReadPressure reads Pressure Stall Information of cgroup,
+// which is available only on cgroup v2 hosts
+func ReadPressure(rootPath, cgroup string) (*PressureStats, error) {
+	if !IsCgroupV2(rootPath) {
+		return nil, errors.New("Pressure Stall Information needs cgroup v2")
+	}
+	dir := filepath.Join(cgroupRoot(rootPath), cgroup)
+	var stats PressureStats
+	var errCPU, errMemory, errIO error
+	stats.CPU, errCPU = ReadPressureFile(filepath.Join(dir, "cpu.pressure"))
+	stats.Memory, errMemory = ReadPressureFile(filepath.Join(dir, "memory.pressure"))
+	stats.IO, errIO = ReadPressureFile(filepath.Join(dir, "io.pressure"))
+	if errCPU != nil && errMemory != nil && errIO != nil {
+		return nil, errCPU
+	}
+	return &stats, nil
+}
+
+// setDelta calculates stall time since previous line,
+// counters are reset when container restarts, so we skip such samples
+func (line *PressureLine) setDelta(prev *PressureLine) {
+	if prev != nil && line.Total >= prev.Total {
+		line.TotalDelta = line.Total - prev.Total
+	}
+}
+
+func (p *Pressure) setDeltas(prev *Pressure) {
+	if p == nil || prev == nil {
+		return
+	}
+	p.Some.setDelta(&prev.Some)
+	if p.Full != nil {
+		p.Full.setDelta(prev.Full)
+	}
+}
+
+// SetDeltas calculates stall times since `prev` sample
+func (stats *PressureStats) SetDeltas(prev *PressureStats) {
+	if stats == nil || prev == nil {
+		return
+	}
+	stats.CPU.setDeltas(prev.CPU)
+	stats.Memory.setDeltas(prev.Memory)
+	stats.IO.setDeltas(prev.IO)
+}
@@ -0,0 +1,56 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+)
+
+func TestReadPressure(t *testing.T) {
+	stats, err := ReadPressure("./testdata/cgroupv2", "/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope")
+	if err != nil {
+		t.Fatal("pressure read fail", err)
+	}
+	expected := PressureLine{Avg10: 12.5, Avg60: 8.25, Avg300: 3.1, Total: 58712030}
+	if stats.CPU.Some != expected {
+		t.Errorf("%v not equal to expected %v", stats.CPU.Some, expected)
+	}
+	if stats.IO.Full == nil || stats.IO.Full.Total != 7710001 {
+		t.Errorf("%v full IO pressure total not equal to expected %d", stats.IO.Full, 7710001)
+	}
+}
+
+func TestReadPressureV1(t *testing.T) {
+	_, err := ReadPressure("./testroot", "/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a")
+	if err == nil {
+		t.Error("ReadPressure on cgroup v1 host didn't failed when expected to fail")
+	}
+}
+
+func TestPressureSetDeltas(t *testing.T) {
+	prev := &PressureStats{
+		CPU:    &Pressure{Some: PressureLine{Total: 1000}, Full: &PressureLine{Total: 100}},
+		Memory: &Pressure{Some: PressureLine{Total: 5000}},
+	}
+	cur := &PressureStats{
+		CPU:    &Pressure{Some: PressureLine{Total: 1500}, Full: &PressureLine{Total: 300}},
+		Memory: &Pressure{Some: PressureLine{Total: 10}},
+		IO:     &Pressure{Some: PressureLine{Total: 10}},
+	}
+	cur.SetDeltas(prev)
+	if cur.CPU.Some.TotalDelta != 500 {
+		t.Errorf("%d not equal to expected %d", cur.CPU.Some.TotalDelta, 500)
+	}
+	if cur.CPU.Full.TotalDelta != 200 {
+		t.Errorf("%d not equal to expected %d", cur.CPU.Full.TotalDelta, 200)
+	}
+	// counter reset after container restart
+	if cur.Memory.Some.TotalDelta != 0 {
+		t.Errorf("%d not equal to expected %d", cur.Memory.Some.TotalDelta, 0)
+	}
+	if cur.IO.Some.TotalDelta != 0 {
+		t.Errorf("%d not equal to expected %d", cur.IO.Some.TotalDelta, 0)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -32,8 +33,34 @@ type Process struct {
 	// 9% of available host CPU resources.
 	RelativeCPUUsage float64 `json:"relativecpuusage"`
 	Cgroup           string  `json:"cgroup"`
+	// Host is set for host processes, which are grouped
+	// under HostContainer pseudo-container
+	Host bool `json:"host,omitempty"`
+	// FDs is missing if /proc/{pid}/fd is not readable
+	FDs *FDStats `json:"fds,omitempty"`
+	// NSpid holds PIDs in nested PID namespaces, host one first
+	NSpid []uint64 `json:"nspid,omitempty"`
+	// ContainerPid is the PID as it is seen inside the container
+	ContainerPid uint64 `json:"containerpid,omitempty"`
+	// Namespaces holds namespace inodes by namespace type
+	Namespaces map[string]uint64 `json:"namespaces,omitempty"`
+	// User and Group are names of real UID and GID in container's
+	// own /etc/passwd and /etc/group, empty if unknown
+	User  string `json:"user,omitempty"`
+	Group string `json:"group,omitempty"`
+	// EffectiveUser is name of effective UID, which differs from
+	// real one for setuid programs
+	EffectiveUser string `json:"effectiveuser,omitempty"`
+	ProcessPaths
+	// Schedstat is missing on kernels without CONFIG_SCHED_INFO
+	Schedstat *Schedstat `json:"schedstat,omitempty"`
+	// Rates are set for the requested interval only
+	Rates *Rates `json:"rates,omitempty"`
 }
 
+// HostContainer is the pseudo-container name for host processes
+const HostContainer = "/host"
+
 // List of Processes
 type List []Process
 
@@ -63,6 +90,30 @@ func (p ByCPU) Less(i, j int) bool {
 	return p[i].RelativeCPUUsage < p[j].RelativeCPUUsage
 }
 
+// top returns first `limit` processes sorted in reverse order by `by`,
+// limit of 0 means all processes
+func (procs List) top(by sort.Interface, limit int) List {
+	sort.Sort(sort.Reverse(by))
+	if limit == 0 || limit > len(procs) {
+		limit = len(procs)
+	}
+	var result List
+	for _, p := range procs[:limit] {
+		result = append(result, p)
+	}
+	return result
+}
+
+// TopCPU returns `limit` processes with top RelativeCPUUsage
+func (procs List) TopCPU(limit int) List {
+	return procs.top(ByCPU(procs), limit)
+}
+
+// TopMem returns `limit` processes with top Status.VmRSS
+func (procs List) TopMem(limit int) List {
+	return procs.top(ByRSS(procs), limit)
+}
+
 // ReadProcessCgroup reads and parses /proc/{pid}/cgroup file
 func ReadProcessCgroup(path string) (string, error) {
 	cgroupPath := fmt.Sprintf(path)
@@ -72,22 +123,53 @@ func ReadProcessCgroup(path string) (string, error) {
 	}
 	cgroupString := string(dataBytes)
 	lines := strings.Split(cgroupString, "\n")
-	var validLine = regexp.MustCompile("^[0-9]+:(.+):(.+)$")
+	var validLine = regexp.MustCompile("^[0-9]+:(.*):(.+)$")
+	unified := "/"
 	for _, l := range lines {
 		m := validLine.FindStringSubmatch(l)
 		if m == nil {
 			continue
 		}
+		// cgroup v2 unified hierarchy has no controllers listed
+		if m[1] == "" {
+			unified = m[2]
+			continue
+		}
 		// we care only about cpu cgroup
 		if strings.Contains(m[1], "cpu") {
 			return m[2], nil
 		}
 	}
-	return "/", nil
+	return unified, nil
 }
 
 // GetProcesses returns list of processes that are in any cgroup
 func GetProcesses(rootPath string) (List, error) {
+	return getProcesses(rootPath, false)
+}
+
+// GetProcessesWithHost returns list of processes that are in any cgroup,
+// along with host processes, marked with Host flag
+func GetProcessesWithHost(rootPath string) (List, error) {
+	return getProcesses(rootPath, true)
+}
+
+// IsHostCgroup tells if cgroup belongs to host rather than to some container,
+// which is the root cgroup and systemd services
+func IsHostCgroup(cgroup string) bool {
+	switch cgroup {
+	case "/", "/init.scope", "/system.slice":
+		return true
+	}
+	if !strings.HasPrefix(cgroup, "/system.slice/") {
+		return false
+	}
+	// /system.slice/docker-{id}.scope and alike are containers
+	unit := strings.Split(strings.TrimPrefix(cgroup, "/system.slice/"), "/")[0]
+	return strings.HasSuffix(unit, ".service")
+}
+
+func getProcesses(rootPath string, includeHost bool) (List, error) {
 	path := filepath.Join(rootPath, "/proc/")
 	d, err := os.Open(path)
 	if err != nil {
@@ -129,8 +211,9 @@ func GetProcesses(rootPath string) (List, error) {
 				var stat *linuxproc.ProcessStat
 				var status *linuxproc.ProcessStatus
 				var cmdline string
-				// we collect only processes from containers
-				if cgroup != "/" {
+				host := includeHost && IsHostCgroup(cgroup)
+				// we collect only processes from containers, unless asked for host ones
+				if cgroup != "/" || host {
 					p := Process{}
 					if stat, err = linuxproc.ReadProcessStat(filepath.Join(path, pid, "stat")); err != nil {
 						continue
@@ -143,9 +226,26 @@ func GetProcesses(rootPath string) (List, error) {
 					}
 					p.Cmdline = cmdline
 					p.Cgroup = cgroup
+					p.Host = host
+					p.FDs, _ = CountProcessFDs(filepath.Join(path, pid))
+					if p.NSpid, err = ReadNSpid(filepath.Join(path, pid, "status")); err == nil && len(p.NSpid) > 0 {
+						p.ContainerPid = p.NSpid[len(p.NSpid)-1]
+					}
+					p.Namespaces = ReadNamespaces(filepath.Join(path, pid))
+					p.ProcessPaths = ReadProcessPaths(filepath.Join(path, pid))
+					p.Schedstat, _ = ReadProcessSchedstat(filepath.Join(path, pid))
 					p.Stat = *stat
 					p.Status = *status
-					if p.Cmdline != "" && p.Status.VmRSS > 0 {
+					if p.IsZombie() {
+						// zombies have neither cmdline nor memory, show them like ps does
+						p.Cmdline = fmt.Sprintf("[%s] <defunct>", p.Status.Name)
+						procs = append(procs, p)
+					} else if p.Stat.State == "Z" {
+						// thread group leader exited, but other threads keep running
+						// without leader's cmdline and memory, show it by name
+						p.Cmdline = fmt.Sprintf("[%s]", p.Status.Name)
+						procs = append(procs, p)
+					} else if p.Cmdline != "" && p.Status.VmRSS > 0 {
 						procs = append(procs, p)
 					}
 				}
@@ -181,7 +281,20 @@ func (procs List) GetCPUTotalUsage() int64 {
 func (procs List) GetCgroupsMap() map[string]List {
 	cgroupsMap := make(map[string]List, 0)
 	for _, p := range procs {
-		cgroupsMap[p.Cgroup] = append(cgroupsMap[p.Cgroup], p)
+		key := p.Cgroup
+		if p.Host {
+			key = HostContainer
+		}
+		cgroupsMap[key] = append(cgroupsMap[key], p)
 	}
 	return cgroupsMap
 }
+
+// GetMemTotal returns total amount of host memory in kB
+func GetMemTotal(rootPath string) (uint64, error) {
+	meminfo, err := linuxproc.ReadMemInfo(filepath.Join(rootPath, "/proc/meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	return meminfo.MemTotal, nil
+}
@@ -52,7 +52,8 @@ func TestGetProcesses(t *testing.T) {
 	if err != nil {
 		t.Fatal("process reading fail", err)
 	}
-	expected := 7
+	// zombie sshd (8350) is collected too
+	expected := 10
 	if len(procs) != expected {
 		t.Errorf("%d not equal to expected %d", len(procs), expected)
 	}
@@ -123,3 +124,47 @@ func TestGetCgroupsMapNormal(t *testing.T) {
 		t.Errorf("%d not equal to expected %d", len(procs), expectedLen)
 	}
 }
+
+func TestIsHostCgroup(t *testing.T) {
+	cgroups := map[string]bool{
+		"/":                                  true,
+		"/init.scope":                        true,
+		"/system.slice":                      true,
+		"/system.slice/cron.service":         true,
+		"/system.slice/docker.service/extra": true,
+		"/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope": false,
+		"/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a":                    false,
+		"/user/0.user/522.session": false,
+	}
+	for cgroup, expected := range cgroups {
+		if IsHostCgroup(cgroup) != expected {
+			t.Errorf("%v not equal to expected %v for %s", !expected, expected, cgroup)
+		}
+	}
+}
+
+func TestGetProcessesWithHost(t *testing.T) {
+	procs, err := GetProcessesWithHost("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	// zombie sshd (8350) is collected too
+	expected := 11
+	if len(procs) != expected {
+		t.Errorf("%d not equal to expected %d", len(procs), expected)
+	}
+	cgroupsMap := procs.GetCgroupsMap()
+	hostProcs, ok := cgroupsMap[HostContainer]
+	if !ok {
+		t.Fatalf("%s not found in cgroupsMap", HostContainer)
+	}
+	expectedLen := 1
+	if len(hostProcs) != expectedLen {
+		t.Fatalf("%d not equal to expected %d", len(hostProcs), expectedLen)
+	}
+	// host processes keep their real cgroup
+	expectedCgroup := "/"
+	if hostProcs[0].Cgroup != expectedCgroup {
+		t.Errorf("%s not equal to expected %s", hostProcs[0].Cgroup, expectedCgroup)
+	}
+}
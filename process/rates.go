@@ -0,0 +1,74 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+// Rates holds per second rates of process lifetime counters
+// over the requested interval
+type Rates struct {
+	VoluntaryCtxtSwitches    float64 `json:"voluntary_ctxt_switches"`
+	NonvoluntaryCtxtSwitches float64 `json:"nonvoluntary_ctxt_switches"`
+	Minflt                   float64 `json:"minflt"`
+	Majflt                   float64 `json:"majflt"`
+}
+
+// GetRates returns per second rates of process counters since
+// `prev` state of the same process taken `seconds` ago
+func (p Process) GetRates(prev Process, seconds float64) *Rates {
+	if seconds <= 0 {
+		return nil
+	}
+	return &Rates{
+		VoluntaryCtxtSwitches:    rate(p.Status.VoluntaryCtxtSwitches, prev.Status.VoluntaryCtxtSwitches, seconds),
+		NonvoluntaryCtxtSwitches: rate(p.Status.NonvoluntaryCtxtSwitches, prev.Status.NonvoluntaryCtxtSwitches, seconds),
+		Minflt:                   rate(p.Stat.Minflt, prev.Stat.Minflt, seconds),
+		Majflt:                   rate(p.Stat.Majflt, prev.Stat.Majflt, seconds),
+	}
+}
+
+// byRate helps us sort array of Process by one of its rates
+type byRate struct {
+	List
+	value func(r *Rates) float64
+}
+
+func (p byRate) Less(i, j int) bool {
+	return p.rate(i) < p.rate(j)
+}
+
+func (p byRate) rate(i int) float64 {
+	if p.List[i].Rates == nil {
+		return 0
+	}
+	return p.value(p.List[i].Rates)
+}
+
+func (p byRate) Len() int {
+	return len(p.List)
+}
+func (p byRate) Swap(i, j int) {
+	p.List[i], p.List[j] = p.List[j], p.List[i]
+}
+
+// TopCtxtSwitches returns `limit` processes with top
+// voluntary and nonvoluntary context switches rate
+func (procs List) TopCtxtSwitches(limit int) List {
+	return procs.top(byRate{procs, func(r *Rates) float64 {
+		return r.VoluntaryCtxtSwitches + r.NonvoluntaryCtxtSwitches
+	}}, limit)
+}
+
+// TopMinflt returns `limit` processes with top minor page faults rate
+func (procs List) TopMinflt(limit int) List {
+	return procs.top(byRate{procs, func(r *Rates) float64 {
+		return r.Minflt
+	}}, limit)
+}
+
+// TopMajflt returns `limit` processes with top major page faults rate
+func (procs List) TopMajflt(limit int) List {
+	return procs.top(byRate{procs, func(r *Rates) float64 {
+		return r.Majflt
+	}}, limit)
+}
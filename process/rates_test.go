@@ -0,0 +1,72 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetRates(t *testing.T) {
+	var prev, cur Process
+	prev.Status.VoluntaryCtxtSwitches = 100
+	prev.Status.NonvoluntaryCtxtSwitches = 10
+	prev.Stat.Minflt = 1000
+	prev.Stat.Majflt = 5
+	cur.Status.VoluntaryCtxtSwitches = 300
+	cur.Status.NonvoluntaryCtxtSwitches = 14
+	cur.Stat.Minflt = 1500
+	cur.Stat.Majflt = 5
+	r := cur.GetRates(prev, 2)
+	expected := Rates{VoluntaryCtxtSwitches: 100, NonvoluntaryCtxtSwitches: 2, Minflt: 250, Majflt: 0}
+	if *r != expected {
+		t.Errorf("%v not equal to expected %v", *r, expected)
+	}
+	if r := cur.GetRates(prev, 0); r != nil {
+		t.Errorf("%v not equal to expected %v", r, nil)
+	}
+}
+
+func TestHistoryRates(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	procs = procs.GetCgroupsMap()["/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"]
+	var next List
+	for _, p := range procs {
+		switch p.Status.Pid {
+		case 8713:
+			// munin-node is thrashing
+			p.Stat.Majflt += 400
+		case 8743:
+			// rsyslogd is contending on lock
+			p.Status.NonvoluntaryCtxtSwitches += 8000
+			p.Stat.Minflt += 200
+		}
+		next = append(next, p)
+	}
+	now := time.Now()
+	var history HistoryDB
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: now, Processes: procs}})
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: now.Add(2 * time.Second), Processes: next}})
+	snap, err := history.GetLastData("test", 1, 1)
+	if err != nil {
+		t.Fatal("getting last data failed", err)
+	}
+	tops := map[uint64]List{
+		8713: snap.Processes.TopMajflt(1),
+		8743: snap.Processes.TopCtxtSwitches(1),
+	}
+	for pid, top := range tops {
+		if len(top) != 1 || top[0].Status.Pid != pid {
+			t.Errorf("%v top process is not expected %d", top, pid)
+		}
+	}
+	top := snap.Processes.TopMinflt(1)
+	if len(top) != 1 || top[0].Rates.Minflt != 100 {
+		t.Errorf("%v is not expected top minflt process", top)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// ContainerRef is the canonical container identity, independent
+// of the way its runtime names cgroups
+type ContainerRef struct {
+	Runtime string `json:"runtime"`
+	ID      string `json:"id"`
+}
+
+// Normalizer extracts container id from raw cgroup path,
+// if the cgroup belongs to its runtime
+type Normalizer func(cgroup string) (string, bool)
+
+type runtimeNormalizer struct {
+	runtime    string
+	normalizer Normalizer
+}
+
+// normalizers are tried in order of registration
+var normalizers []runtimeNormalizer
+
+// RegisterNormalizer adds cgroup normalizer for runtime. Normalizers are
+// tried in order of registration, so more specific ones go first
+func RegisterNormalizer(runtime string, n Normalizer) {
+	normalizers = append(normalizers, runtimeNormalizer{runtime, n})
+}
+
+// NormalizeCgroup maps raw cgroup path to the canonical (runtime, id) pair
+func NormalizeCgroup(cgroup string) (ContainerRef, bool) {
+	for _, n := range normalizers {
+		if id, ok := n.normalizer(cgroup); ok {
+			return ContainerRef{n.runtime, id}, true
+		}
+	}
+	return ContainerRef{}, false
+}
+
+// regexpNormalizer returns Normalizer, which takes container id from
+// the first submatch of the first matching regexp
+func regexpNormalizer(patterns ...string) Normalizer {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		res = append(res, regexp.MustCompile(p))
+	}
+	return func(cgroup string) (string, bool) {
+		for _, re := range res {
+			if m := re.FindStringSubmatch(cgroup); m != nil {
+				return m[1], true
+			}
+		}
+		return "", false
+	}
+}
+
+// systemdEscape matches \xNN escapes systemd uses in unit names
+var systemdEscape = regexp.MustCompile("\\\\x[0-9a-fA-F]{2}")
+
+// unescapeSystemd decodes \xNN escapes systemd uses in unit names
+func unescapeSystemd(name string) string {
+	return systemdEscape.ReplaceAllStringFunc(name, func(escape string) string {
+		b, _ := strconv.ParseUint(escape[2:], 16, 8)
+		return string([]byte{byte(b)})
+	})
+}
+
+// machineNormalizer handles systemd-machined registered containers,
+// like systemd-nspawn and libvirt-lxc ones
+func machineNormalizer(cgroup string) (string, bool) {
+	id, ok := regexpNormalizer(
+		"^/machine\\.slice/machine-([^/]+)\\.scope(?:/|$)",
+		"^/machine\\.slice/systemd-nspawn@([^/]+)\\.service(?:/|$)",
+	)(cgroup)
+	return unescapeSystemd(id), ok
+}
+
+func init() {
+	// podman runs containers both under machine.slice and user slices,
+	// so it goes before generic machine.slice
+	RegisterNormalizer("docker", regexpNormalizer(
+		"(?:^|/)docker[-/]([0-9a-f]{64})(?:\\.scope)?$",
+	))
+	// kubelet cgroupfs driver names containerd containers by bare id
+	RegisterNormalizer("containerd", regexpNormalizer(
+		"/cri-containerd-([0-9a-f]{64})\\.scope$",
+		"^/kubepods/(?:[^/]+/)?pod[0-9a-f-]+/([0-9a-f]{64})$",
+	))
+	RegisterNormalizer("cri-o", regexpNormalizer(
+		"/crio-([0-9a-f]{64})(?:\\.scope)?$",
+	))
+	RegisterNormalizer("podman", regexpNormalizer(
+		"/libpod-([0-9a-f]{64})\\.scope(?:/container)?$",
+		"^/libpod_parent/libpod-([0-9a-f]{64})(?:/ctr)?$",
+	))
+	RegisterNormalizer("lxc", regexpNormalizer(
+		"^/lxc/([^/]+)(?:/|$)",
+		"^/lxc\\.payload[./]([^/]+)(?:/|$)",
+	))
+	RegisterNormalizer("machine", machineNormalizer)
+}
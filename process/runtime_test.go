@@ -0,0 +1,98 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeCgroupFixtures(t *testing.T) {
+	id := "7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad"
+	fixtures := map[string]ContainerRef{
+		"docker-cgroupfs":     {"docker", id},
+		"docker-systemd":      {"docker", id},
+		"docker-v2":           {"docker", id},
+		"containerd-v2":       {"containerd", id},
+		"containerd-cgroupfs": {"containerd", id},
+		"crio-cgroupfs":       {"cri-o", id},
+		"crio-systemd":        {"cri-o", id},
+		"podman-v2":           {"podman", id},
+		"podman-rootless":     {"podman", id},
+		"podman-cgroupfs":     {"podman", id},
+		"lxc":                 {"lxc", "web01"},
+		"lxc-v2":              {"lxc", "web01"},
+		"nspawn-machine":      {"machine", "debian-test"},
+		"nspawn-service":      {"machine", "debian-test"},
+		"nspawn-escaped":      {"machine", "web-front+01"},
+	}
+	for fixture, expected := range fixtures {
+		cgroup, err := ReadProcessCgroup(filepath.Join("./testdata/cgroup", fixture))
+		if err != nil {
+			t.Fatal("process cgroup read fail", err)
+		}
+		ref, ok := NormalizeCgroup(cgroup)
+		if !ok {
+			t.Errorf("%s cgroup %s not normalized", fixture, cgroup)
+			continue
+		}
+		if ref != expected {
+			t.Errorf("%v not equal to expected %v for %s", ref, expected, fixture)
+		}
+	}
+}
+
+func TestNormalizeCgroupNone(t *testing.T) {
+	cgroup, err := ReadProcessCgroup("./testdata/cgroup/none")
+	if err != nil {
+		t.Fatal("process cgroup read fail", err)
+	}
+	if ref, ok := NormalizeCgroup(cgroup); ok {
+		t.Errorf("%s normalized to %v when should not", cgroup, ref)
+	}
+}
+
+func TestNormalizeCgroupConmon(t *testing.T) {
+	cgroup := "/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod0b1f2c9e_4d6a_4c1b_9a8e_2f3d4c5b6a71.slice/crio-conmon-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope"
+	if ref, ok := NormalizeCgroup(cgroup); ok {
+		t.Errorf("%s normalized to %v when should not", cgroup, ref)
+	}
+}
+
+func TestRegisterNormalizer(t *testing.T) {
+	saved := normalizers
+	defer func() { normalizers = saved }()
+	RegisterNormalizer("custom", func(cgroup string) (string, bool) {
+		return "id", cgroup == "/custom"
+	})
+	expected := ContainerRef{"custom", "id"}
+	ref, ok := NormalizeCgroup("/custom")
+	if !ok || ref != expected {
+		t.Errorf("%v not equal to expected %v", ref, expected)
+	}
+}
+
+func TestReadProcessCgroupUnified(t *testing.T) {
+	cgroup, err := ReadProcessCgroup("./testdata/cgroup/docker-v2")
+	if err != nil {
+		t.Fatal("process cgroup read fail", err)
+	}
+	expected := "/system.slice/docker-7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad.scope"
+	if cgroup != expected {
+		t.Errorf("%s not equal to expected %s", cgroup, expected)
+	}
+}
+
+func TestReadProcessCgroupHybrid(t *testing.T) {
+	// cpu controller is still mounted as v1 on hybrid hosts
+	cgroup, err := ReadProcessCgroup("./testdata/cgroup/docker-hybrid")
+	if err != nil {
+		t.Fatal("process cgroup read fail", err)
+	}
+	expected := "/docker/7dc0bf65ec30b7d45868963cf1186a18a42fcf30d5a2df2002678bd0a1b31cad"
+	if cgroup != expected {
+		t.Errorf("%s not equal to expected %s", cgroup, expected)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Schedstat holds scheduler statistics from /proc/{pid}/schedstat
+type Schedstat struct {
+	// RunTime is time spent on CPU, in nanoseconds
+	RunTime uint64 `json:"runtime"`
+	// WaitTime is time spent waiting on run queue, in nanoseconds
+	WaitTime   uint64 `json:"waittime"`
+	Timeslices uint64 `json:"timeslices"`
+	// Deltas are set for the requested interval only
+	RunTimeDelta    uint64 `json:"runtime_delta"`
+	WaitTimeDelta   uint64 `json:"waittime_delta"`
+	TimeslicesDelta uint64 `json:"timeslices_delta"`
+	// WaitRatio is run queue wait time to run time ratio over the interval,
+	// process is starved for CPU when it is high
+	WaitRatio float64 `json:"wait_ratio"`
+}
+
+// ByWaitRatio helps us sort array of Process by Schedstat.WaitRatio
+type ByWaitRatio List
+
+func (p ByWaitRatio) Len() int {
+	return len(p)
+}
+func (p ByWaitRatio) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+func (p ByWaitRatio) Less(i, j int) bool {
+	return p[i].waitRatio() < p[j].waitRatio()
+}
+
+func (p Process) waitRatio() float64 {
+	if p.Schedstat == nil {
+		return 0
+	}
+	return p.Schedstat.WaitRatio
+}
+
+// TopWait returns `limit` processes with top Schedstat.WaitRatio
+func (procs List) TopWait(limit int) List {
+	return procs.top(ByWaitRatio(procs), limit)
+}
+
+// ReadSchedstat parses /proc/{pid}/schedstat file
+func ReadSchedstat(path string) (*Schedstat, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(dataBytes))
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("Wrong schedstat format in %s", path)
+	}
+	var values [3]uint64
+	for i := range values {
+		if values[i], err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return &Schedstat{RunTime: values[0], WaitTime: values[1], Timeslices: values[2]}, nil
+}
+
+// ReadProcessSchedstat sums scheduler statistics of all process threads
+// from /proc/{pid}/task/*/schedstat, as /proc/{pid}/schedstat covers
+// only the main thread. path is /proc/{pid}
+func ReadProcessSchedstat(path string) (*Schedstat, error) {
+	files, _ := filepath.Glob(filepath.Join(path, "task", "*", "schedstat"))
+	if len(files) == 0 {
+		return ReadSchedstat(filepath.Join(path, "schedstat"))
+	}
+	var sum Schedstat
+	found := false
+	for _, f := range files {
+		// thread may exit while we are reading
+		s, err := ReadSchedstat(f)
+		if err != nil {
+			continue
+		}
+		found = true
+		sum.RunTime += s.RunTime
+		sum.WaitTime += s.WaitTime
+		sum.Timeslices += s.Timeslices
+	}
+	if !found {
+		return nil, fmt.Errorf("No readable schedstat in %s", path)
+	}
+	return &sum, nil
+}
+
+// setRatio calculates WaitRatio from deltas
+func (s *Schedstat) setRatio() {
+	s.WaitRatio = 0
+	if s.RunTimeDelta != 0 {
+		s.WaitRatio = float64(s.WaitTimeDelta) / float64(s.RunTimeDelta)
+	}
+}
+
+// delta returns counter growth, sums over threads may
+// decrease when threads exit, so decrease is treated as 0
+func delta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+// Delta returns copy of stats with deltas since `prev` stats,
+// nil `prev` means process started during the interval
+func (s *Schedstat) Delta(prev *Schedstat) *Schedstat {
+	if s == nil {
+		return nil
+	}
+	result := *s
+	result.RunTimeDelta = s.RunTime
+	result.WaitTimeDelta = s.WaitTime
+	result.TimeslicesDelta = s.Timeslices
+	if prev != nil {
+		result.RunTimeDelta = delta(s.RunTime, prev.RunTime)
+		result.WaitTimeDelta = delta(s.WaitTime, prev.WaitTime)
+		result.TimeslicesDelta = delta(s.Timeslices, prev.Timeslices)
+	}
+	result.setRatio()
+	return &result
+}
+
+// GetSchedDelta returns summed scheduler statistics deltas of procs
+// since `prev` list was taken, matching processes like GetCPUTimeDelta
+func (procs List) GetSchedDelta(prev List) Schedstat {
+	var sum Schedstat
+	for _, p := range procs {
+		var prevStats *Schedstat
+		if pp := prev.FindProc(p.Status.Pid); pp != nil && pp.Stat.Starttime == p.Stat.Starttime {
+			prevStats = pp.Schedstat
+		}
+		if d := p.Schedstat.Delta(prevStats); d != nil {
+			sum.RunTime += d.RunTime
+			sum.WaitTime += d.WaitTime
+			sum.Timeslices += d.Timeslices
+			sum.RunTimeDelta += d.RunTimeDelta
+			sum.WaitTimeDelta += d.WaitTimeDelta
+			sum.TimeslicesDelta += d.TimeslicesDelta
+		}
+	}
+	sum.setRatio()
+	return sum
+}
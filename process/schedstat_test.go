@@ -0,0 +1,150 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadSchedstat(t *testing.T) {
+	s, err := ReadSchedstat("./testroot/proc/8743/schedstat")
+	if err != nil {
+		t.Fatal("schedstat read fail", err)
+	}
+	expected := Schedstat{RunTime: 2450119210, WaitTime: 118763425, Timeslices: 20512}
+	if *s != expected {
+		t.Errorf("%v not equal to expected %v", *s, expected)
+	}
+	if _, err := ReadSchedstat("./testroot/proc/8743/status"); err == nil {
+		t.Error("ReadSchedstat for wrong file didn't failed when expected to fail")
+	}
+}
+
+func TestReadProcessSchedstat(t *testing.T) {
+	// all threads are summed up
+	s, err := ReadProcessSchedstat("./testroot/proc/8743")
+	if err != nil {
+		t.Fatal("process schedstat read fail", err)
+	}
+	expected := Schedstat{RunTime: 4000000000, WaitTime: 1100000000, Timeslices: 40000}
+	if *s != expected {
+		t.Errorf("%v not equal to expected %v", *s, expected)
+	}
+	// main thread stats are used if task dir is not readable
+	s, err = ReadProcessSchedstat("./testroot/proc/8713")
+	if err != nil {
+		t.Fatal("process schedstat read fail", err)
+	}
+	leader, _ := ReadSchedstat("./testroot/proc/8713/schedstat")
+	if *s != *leader {
+		t.Errorf("%v not equal to expected %v", *s, *leader)
+	}
+}
+
+func TestSchedstatDelta(t *testing.T) {
+	prev := &Schedstat{RunTime: 1000, WaitTime: 500, Timeslices: 10}
+	cur := &Schedstat{RunTime: 3000, WaitTime: 4500, Timeslices: 30}
+	d := cur.Delta(prev)
+	if d.RunTimeDelta != 2000 || d.WaitTimeDelta != 4000 || d.TimeslicesDelta != 20 || d.WaitRatio != 2 {
+		t.Errorf("%v is not expected delta", *d)
+	}
+	// original stats are kept intact
+	if cur.RunTimeDelta != 0 {
+		t.Errorf("%d not equal to expected %d", cur.RunTimeDelta, 0)
+	}
+	// process started during the interval counts in full
+	if d := cur.Delta(nil); d.RunTimeDelta != 3000 || d.WaitRatio != 1.5 {
+		t.Errorf("%v is not expected delta", *d)
+	}
+	// counters summed over threads decrease when threads exit
+	if d := prev.Delta(cur); d.RunTimeDelta != 0 || d.WaitTimeDelta != 0 || d.TimeslicesDelta != 0 {
+		t.Errorf("%v is not expected delta", *d)
+	}
+	var missing *Schedstat
+	if d := missing.Delta(prev); d != nil {
+		t.Errorf("%v not equal to expected %v", d, nil)
+	}
+}
+
+// prepareSchedHistory returns history with two entries,
+// where rsyslogd (8743) waited for CPU a lot
+func prepareSchedHistory() (*HistoryDB, error) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		return nil, err
+	}
+	procs = procs.GetCgroupsMap()["/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"]
+	var next List
+	for _, p := range procs {
+		s := *p.Schedstat
+		s.RunTime += 1000
+		if p.Status.Pid == 8743 {
+			s.WaitTime += 3000
+		} else {
+			s.WaitTime += 100
+		}
+		p.Schedstat = &s
+		next = append(next, p)
+	}
+	var history HistoryDB
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now(), Processes: procs}})
+	history.Push(HistoryEntry{"test": Snapshot{Timestamp: time.Now(), Processes: next}})
+	return &history, nil
+}
+
+func TestHistorySchedstat(t *testing.T) {
+	history, err := prepareSchedHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	snap, err := history.GetLastData("test", 1, 1)
+	if err != nil {
+		t.Fatal("getting last data failed", err)
+	}
+	top := snap.Processes.TopWait(1)
+	if len(top) != 1 || top[0].Status.Pid != 8743 || top[0].Schedstat.WaitRatio != 3 {
+		t.Errorf("%v is not expected top waiting process", top)
+	}
+	// history keeps raw counters
+	if history[len(history)-1]["test"].Processes[0].Schedstat.RunTimeDelta != 0 {
+		t.Error("GetLastData modified history")
+	}
+
+	aggregates, err := history.GetAggregates("test")
+	if err != nil {
+		t.Fatal("getting history aggregates failed", err)
+	}
+	last := aggregates[len(aggregates)-1]
+	if last.SchedRunTimeDelta != 3000 || last.SchedWaitTimeDelta != 3200 {
+		t.Errorf("%d/%d not equal to expected %d/%d", last.SchedRunTimeDelta, last.SchedWaitTimeDelta, 3000, 3200)
+	}
+}
+
+func TestHistorySchedstatReusedPid(t *testing.T) {
+	history, err := prepareSchedHistory()
+	if err != nil {
+		t.Fatal("preparing history failed", err)
+	}
+	// process with the same PID but other start time is a new process
+	last := history[len(history)-1]["test"]
+	for i := range last.Processes {
+		p := &last.Processes[i]
+		p.Stat.Starttime++
+		s := *p.Schedstat
+		s.RunTime = 10
+		s.WaitTime = 20
+		p.Schedstat = &s
+	}
+	snap, err := history.GetLastData("test", 1, 1)
+	if err != nil {
+		t.Fatal("getting last data failed", err)
+	}
+	for _, p := range snap.Processes {
+		if p.Schedstat.RunTimeDelta != 10 || p.Schedstat.WaitTimeDelta != 20 {
+			t.Errorf("%v is not expected delta for process %d", *p.Schedstat, p.Status.Pid)
+		}
+	}
+}
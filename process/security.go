@@ -0,0 +1,188 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// capabilityNames are capability names by their bit number,
+// as defined in linux/capability.h
+var capabilityNames = []string{
+	"CAP_CHOWN",
+	"CAP_DAC_OVERRIDE",
+	"CAP_DAC_READ_SEARCH",
+	"CAP_FOWNER",
+	"CAP_FSETID",
+	"CAP_KILL",
+	"CAP_SETGID",
+	"CAP_SETUID",
+	"CAP_SETPCAP",
+	"CAP_LINUX_IMMUTABLE",
+	"CAP_NET_BIND_SERVICE",
+	"CAP_NET_BROADCAST",
+	"CAP_NET_ADMIN",
+	"CAP_NET_RAW",
+	"CAP_IPC_LOCK",
+	"CAP_IPC_OWNER",
+	"CAP_SYS_MODULE",
+	"CAP_SYS_RAWIO",
+	"CAP_SYS_CHROOT",
+	"CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT",
+	"CAP_SYS_ADMIN",
+	"CAP_SYS_BOOT",
+	"CAP_SYS_NICE",
+	"CAP_SYS_RESOURCE",
+	"CAP_SYS_TIME",
+	"CAP_SYS_TTY_CONFIG",
+	"CAP_MKNOD",
+	"CAP_LEASE",
+	"CAP_AUDIT_WRITE",
+	"CAP_AUDIT_CONTROL",
+	"CAP_SETFCAP",
+	"CAP_MAC_OVERRIDE",
+	"CAP_MAC_ADMIN",
+	"CAP_SYSLOG",
+	"CAP_WAKE_ALARM",
+	"CAP_BLOCK_SUSPEND",
+	"CAP_AUDIT_READ",
+	"CAP_PERFMON",
+	"CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+// DangerousCapabilities let root in container escape it or
+// attack host and neighbour containers
+var DangerousCapabilities = map[string]bool{
+	"CAP_SYS_ADMIN":       true,
+	"CAP_SYS_MODULE":      true,
+	"CAP_SYS_PTRACE":      true,
+	"CAP_SYS_RAWIO":       true,
+	"CAP_DAC_READ_SEARCH": true,
+	"CAP_NET_ADMIN":       true,
+	"CAP_NET_RAW":         true,
+	"CAP_BPF":             true,
+}
+
+// seccompModes are names of Seccomp field values of /proc/{pid}/status
+var seccompModes = []string{"disabled", "strict", "filter"}
+
+// Security holds decoded security attributes of process
+type Security struct {
+	CapEff     []string `json:"capeff"`
+	CapPrm     []string `json:"capprm"`
+	CapBnd     []string `json:"capbnd"`
+	Seccomp    string   `json:"seccomp"`
+	NoNewPrivs bool     `json:"nonewprivs"`
+	// Label is AppArmor profile or SELinux context, if any
+	Label string `json:"label,omitempty"`
+}
+
+// ProcessSecurity is security report item for single process
+type ProcessSecurity struct {
+	Pid     uint64 `json:"pid"`
+	Cmdline string `json:"cmdline"`
+	// Uid and User are effective UID and its name,
+	// RealUid and RealUser differ from them for setuid programs
+	Uid      uint64   `json:"uid"`
+	User     string   `json:"user,omitempty"`
+	RealUid  uint64   `json:"realuid"`
+	RealUser string   `json:"realuser,omitempty"`
+	Security Security `json:"security"`
+	// Dangerous are dangerous effective capabilities
+	Dangerous []string `json:"dangerous,omitempty"`
+	// Highlight is set for root processes with dangerous capabilities
+	Highlight bool `json:"highlight"`
+}
+
+// SecurityReport holds security posture of container processes
+type SecurityReport struct {
+	// Highlighted is the count of highlighted processes
+	Highlighted int               `json:"highlighted"`
+	Processes   []ProcessSecurity `json:"processes"`
+}
+
+// DecodeCapabilities returns names of capabilities set in mask
+func DecodeCapabilities(mask uint64) []string {
+	result := make([]string, 0)
+	for bit := uint(0); bit < 64; bit++ {
+		if mask&(1<<bit) == 0 {
+			continue
+		}
+		if int(bit) < len(capabilityNames) {
+			result = append(result, capabilityNames[bit])
+		} else {
+			result = append(result, fmt.Sprintf("CAP_%d", bit))
+		}
+	}
+	return result
+}
+
+// SeccompMode returns name of seccomp mode
+func SeccompMode(mode uint8) string {
+	if int(mode) < len(seccompModes) {
+		return seccompModes[mode]
+	}
+	return strconv.Itoa(int(mode))
+}
+
+// ReadSecurityLabel reads AppArmor or SELinux label of process
+// from /proc/{pid}/attr/current file
+func ReadSecurityLabel(path string) (string, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(dataBytes), "\x00\n"), nil
+}
+
+// GetSecurity returns decoded security attributes of process,
+// reading ones missing in process status from /proc
+func GetSecurity(rootPath string, p Process) Security {
+	path := filepath.Join(rootPath, "/proc", strconv.FormatUint(p.Status.Pid, 10))
+	s := Security{
+		CapEff:  DecodeCapabilities(p.Status.CapEff),
+		CapPrm:  DecodeCapabilities(p.Status.CapPrm),
+		CapBnd:  DecodeCapabilities(p.Status.CapBnd),
+		Seccomp: SeccompMode(p.Status.Seccomp),
+	}
+	if value, err := readStatusField(filepath.Join(path, "status"), "NoNewPrivs"); err == nil {
+		s.NoNewPrivs = value == "1"
+	}
+	s.Label, _ = ReadSecurityLabel(filepath.Join(path, "attr", "current"))
+	return s
+}
+
+// GetSecurityReport returns security posture of processes
+func GetSecurityReport(rootPath string, procs List) SecurityReport {
+	report := SecurityReport{Processes: make([]ProcessSecurity, 0)}
+	for _, p := range procs {
+		ps := ProcessSecurity{
+			Pid:      p.Status.Pid,
+			Cmdline:  p.Cmdline,
+			Uid:      p.Status.EffectiveUid,
+			User:     p.EffectiveUser,
+			RealUid:  p.Status.RealUid,
+			RealUser: p.User,
+			Security: GetSecurity(rootPath, p),
+		}
+		for _, c := range ps.Security.CapEff {
+			if DangerousCapabilities[c] {
+				ps.Dangerous = append(ps.Dangerous, c)
+			}
+		}
+		if ps.Uid == 0 && len(ps.Dangerous) > 0 {
+			ps.Highlight = true
+			report.Highlighted++
+		}
+		report.Processes = append(report.Processes, ps)
+	}
+	return report
+}
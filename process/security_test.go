@@ -0,0 +1,90 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCapabilities(t *testing.T) {
+	caps := DecodeCapabilities(0x3000 | 1<<21 | 1<<63)
+	expected := []string{"CAP_NET_ADMIN", "CAP_NET_RAW", "CAP_SYS_ADMIN", "CAP_63"}
+	if !reflect.DeepEqual(caps, expected) {
+		t.Errorf("%v not equal to expected %v", caps, expected)
+	}
+	if caps := DecodeCapabilities(0); len(caps) != 0 {
+		t.Errorf("%v not equal to expected %v", caps, []string{})
+	}
+}
+
+func TestSeccompMode(t *testing.T) {
+	modes := map[uint8]string{0: "disabled", 2: "filter", 5: "5"}
+	for mode, expected := range modes {
+		if SeccompMode(mode) != expected {
+			t.Errorf("%v not equal to expected %v", SeccompMode(mode), expected)
+		}
+	}
+}
+
+func TestGetSecurity(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	s := GetSecurity("./testroot", *procs.FindProc(8743))
+	if s.Seccomp != "disabled" || s.NoNewPrivs || s.Label != "docker-default (enforce)" {
+		t.Errorf("%v is not expected security of rsyslogd", s)
+	}
+	if len(s.CapEff) != 0 || len(s.CapBnd) != 15 {
+		t.Errorf("%v is not expected capabilities of rsyslogd", s)
+	}
+	// sshd privilege separated child is sandboxed with seccomp
+	s = GetSecurity("./testroot", *procs.FindProc(8351))
+	if s.Seccomp != "filter" || !s.NoNewPrivs || s.Label != "" {
+		t.Errorf("%v is not expected security of sshd child", s)
+	}
+	if len(s.CapEff) != 0 || len(s.CapPrm) != 0 {
+		t.Errorf("%v is not expected capabilities of sshd child", s)
+	}
+}
+
+func TestGetSecurityReport(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	procs = procs.GetCgroupsMap()["/docker/325898765f2a47af0ea45addd6632d8ea555b9615f83a3bd38857b6c4cabb53a"]
+	report := GetSecurityReport("./testroot", procs)
+	// munin-node and runsvdir run as root with CAP_NET_ADMIN and CAP_NET_RAW
+	expected := 2
+	if report.Highlighted != expected {
+		t.Errorf("%d not equal to expected %d", report.Highlighted, expected)
+	}
+	for _, ps := range report.Processes {
+		if ps.Highlight != (ps.Pid != 8743) {
+			t.Errorf("%v highlight is not expected", ps)
+		}
+		if ps.Highlight && !reflect.DeepEqual(ps.Dangerous, []string{"CAP_NET_ADMIN", "CAP_NET_RAW"}) {
+			t.Errorf("%v not equal to expected %v", ps.Dangerous, []string{"CAP_NET_ADMIN", "CAP_NET_RAW"})
+		}
+	}
+}
+
+func TestGetSecurityReportSetuid(t *testing.T) {
+	p := Process{User: "alice", EffectiveUser: "root"}
+	p.Status.Pid = 1
+	p.Status.RealUid = 1000
+	p.Status.EffectiveUid = 0
+	p.Status.CapEff = 1 << 21
+	report := GetSecurityReport("./testroot", List{p})
+	ps := report.Processes[0]
+	if ps.Uid != 0 || ps.User != "root" || ps.RealUid != 1000 || ps.RealUser != "alice" {
+		t.Errorf("%v doesn't have expected effective and real users", ps)
+	}
+	if !ps.Highlight {
+		t.Errorf("%v highlight is not expected", ps)
+	}
+}
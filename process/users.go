@@ -0,0 +1,108 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Names maps numeric user or group IDs to names
+type Names map[uint64]string
+
+// accounts holds users and groups of a single filesystem
+type accounts struct {
+	users  Names
+	groups Names
+}
+
+// NamesCache keeps users and groups of container filesystems by
+// mount namespace inode, so files are read once per namespace.
+// Zero value is ready to use, it is not safe for concurrent use
+type NamesCache struct {
+	namespaces map[uint64]*accounts
+}
+
+// ReadNames parses /etc/passwd or /etc/group like file
+func ReadNames(path string) (Names, error) {
+	dataBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make(Names)
+	for _, l := range strings.Split(string(dataBytes), "\n") {
+		if strings.HasPrefix(l, "#") {
+			continue
+		}
+		// name:password:id:...
+		fields := strings.Split(l, ":")
+		if len(fields) < 3 {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		// first entry wins, like in getpwuid
+		if _, ok := names[id]; !ok {
+			names[id] = fields[0]
+		}
+	}
+	return names, nil
+}
+
+// readAccounts reads users and groups of filesystem mounted at root,
+// returns nil if there is no readable /etc/passwd
+func readAccounts(root string) *accounts {
+	users, err := ReadNames(filepath.Join(root, "etc", "passwd"))
+	if err != nil {
+		return nil
+	}
+	groups, _ := ReadNames(filepath.Join(root, "etc", "group"))
+	return &accounts{users: users, groups: groups}
+}
+
+// Resolve sets User, EffectiveUser and Group of processes using /etc/passwd and
+// /etc/group from their own root filesystem, falling back to
+// the ones from rootPath. Namespaces which are gone are dropped from cache
+func (cache *NamesCache) Resolve(rootPath string, procs List) {
+	var host *accounts
+	getHost := func() *accounts {
+		if host == nil {
+			if host = readAccounts(rootPath); host == nil {
+				host = &accounts{}
+			}
+		}
+		return host
+	}
+
+	// root of some processes in namespace may be unreadable,
+	// so try all of them before falling back to host
+	seen := make(map[uint64]*accounts)
+	for _, p := range procs {
+		mnt, ok := p.Namespaces["mnt"]
+		if !ok || seen[mnt] != nil {
+			continue
+		}
+		if a := cache.namespaces[mnt]; a != nil {
+			seen[mnt] = a
+		} else if a := readAccounts(filepath.Join(rootPath, "/proc", strconv.FormatUint(p.Status.Pid, 10), "root")); a != nil {
+			seen[mnt] = a
+		}
+	}
+	for i := range procs {
+		p := &procs[i]
+		a, ok := seen[p.Namespaces["mnt"]]
+		if !ok {
+			a = getHost()
+		}
+		p.User = a.users[p.Status.RealUid]
+		p.EffectiveUser = a.users[p.Status.EffectiveUid]
+		p.Group = a.groups[p.Status.RealGid]
+	}
+	cache.namespaces = seen
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadNames(t *testing.T) {
+	names, err := ReadNames("./testroot/proc/8743/root/etc/passwd")
+	if err != nil {
+		t.Fatal("passwd read fail", err)
+	}
+	expected := Names{0: "root", 101: "syslog", 102: "munin"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("%v not equal to expected %v", names, expected)
+	}
+	if _, err := ReadNames("./testroot/proc/2309/root/etc/passwd"); err == nil {
+		t.Error("ReadNames for missing file didn't failed when expected to fail")
+	}
+}
+
+func TestResolveNames(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	var cache NamesCache
+	cache.Resolve("./testroot", procs)
+	expected := map[uint64][2]string{
+		// own /etc/passwd of container
+		8743: {"syslog", "syslog"},
+		// mount namespace shared with 8743
+		8713: {"root", "root"},
+		// no mount namespace, host /etc/passwd
+		22291: {"root", "root"},
+	}
+	for pid, names := range expected {
+		p := procs.FindProc(pid)
+		if p == nil {
+			t.Fatalf("process %d not found", pid)
+		}
+		if p.User != names[0] || p.Group != names[1] {
+			t.Errorf("%v:%v not equal to expected %v:%v", p.User, p.Group, names[0], names[1])
+		}
+		if p.EffectiveUser != p.User {
+			t.Errorf("%v not equal to expected %v", p.EffectiveUser, p.User)
+		}
+	}
+	if len(cache.namespaces) != 1 {
+		t.Errorf("%d not equal to expected %d", len(cache.namespaces), 1)
+	}
+	// namespaces which are gone are dropped from cache
+	cache.Resolve("./testroot", nil)
+	if len(cache.namespaces) != 0 {
+		t.Errorf("%d not equal to expected %d", len(cache.namespaces), 0)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"sort"
+)
+
+// ZombieParent is a process failing to reap its zombie children
+type ZombieParent struct {
+	Pid uint64 `json:"pid"`
+	// Cmdline is empty if parent is not in the same container,
+	// like container runtime shim for container init
+	Cmdline string   `json:"cmdline"`
+	Zombies []uint64 `json:"zombies"`
+}
+
+// ZombieReport holds zombies of container grouped by their parents
+type ZombieReport struct {
+	Count   int            `json:"count"`
+	Parents []ZombieParent `json:"parents"`
+}
+
+// IsZombie tells if process has exited, but was not reaped by its parent.
+// Thread group leader which exited before other threads is in Z state too,
+// but the process is still alive
+func (p Process) IsZombie() bool {
+	return p.Stat.State == "Z" && p.Status.Threads <= 1
+}
+
+// Zombies returns zombie processes
+func (procs List) Zombies() List {
+	var result List
+	for _, p := range procs {
+		if p.IsZombie() {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// byZombies helps us sort zombie parents by count of their zombies
+type byZombies []ZombieParent
+
+func (p byZombies) Len() int {
+	return len(p)
+}
+func (p byZombies) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+func (p byZombies) Less(i, j int) bool {
+	return len(p[i].Zombies) < len(p[j].Zombies)
+}
+
+// GetZombieReport returns zombies attributed to their parents,
+// parents with most zombies first
+func (procs List) GetZombieReport() ZombieReport {
+	report := ZombieReport{Parents: make([]ZombieParent, 0)}
+	parents := make(map[uint64]*ZombieParent)
+	var order []uint64
+	for _, p := range procs.Zombies() {
+		report.Count++
+		ppid := uint64(p.Status.PPid)
+		parent, ok := parents[ppid]
+		if !ok {
+			parent = &ZombieParent{Pid: ppid}
+			if pp := procs.FindProc(ppid); pp != nil {
+				parent.Cmdline = pp.Cmdline
+			}
+			parents[ppid] = parent
+			order = append(order, ppid)
+		}
+		parent.Zombies = append(parent.Zombies, p.Status.Pid)
+	}
+	for _, ppid := range order {
+		report.Parents = append(report.Parents, *parents[ppid])
+	}
+	sort.Stable(sort.Reverse(byZombies(report.Parents)))
+	return report
+}
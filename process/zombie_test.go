@@ -0,0 +1,94 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetProcessesZombie(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	p := procs.FindProc(8350)
+	if p == nil {
+		t.Fatal("zombie process 8350 not found")
+	}
+	if !p.IsZombie() {
+		t.Errorf("%v is not zombie", p)
+	}
+	expected := "[sshd] <defunct>"
+	if p.Cmdline != expected {
+		t.Errorf("%s not equal to expected %s", p.Cmdline, expected)
+	}
+	if len(procs.Zombies()) != 1 {
+		t.Errorf("%d not equal to expected %d", len(procs.Zombies()), 1)
+	}
+}
+
+func TestIsZombieLiveThreads(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	p := *procs.FindProc(8350)
+	// leader exited, but other threads are still running
+	p.Status.Threads = 3
+	if p.IsZombie() {
+		t.Errorf("%v with live threads is zombie", p)
+	}
+}
+
+func TestGetProcessesExitedLeader(t *testing.T) {
+	procs, err := GetProcesses("./testdata/threads")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	// leader exited, but 3 other threads are still running
+	p := procs.FindProc(8360)
+	if p == nil {
+		t.Fatal("process 8360 with exited leader not found")
+	}
+	expected := "[worker]"
+	if p.Cmdline != expected {
+		t.Errorf("%s not equal to expected %s", p.Cmdline, expected)
+	}
+	if len(procs.Zombies()) != 0 {
+		t.Errorf("%d not equal to expected %d", len(procs.Zombies()), 0)
+	}
+}
+
+func TestGetZombieReport(t *testing.T) {
+	procs, err := GetProcesses("./testroot")
+	if err != nil {
+		t.Fatal("process reading fail", err)
+	}
+	procs = procs.GetCgroupsMap()["/docker/ac4cd7be58276b4f801a86b52b83319c7e7b08e4e4eed15bbe1122355325e3e1"]
+	// second zombie with parent outside of container
+	orphan := *procs.FindProc(8350)
+	orphan.Status.Pid = 8351
+	orphan.Status.PPid = 1
+	// third zombie of sshd
+	zombie := *procs.FindProc(8350)
+	zombie.Status.Pid = 8352
+	procs = append(procs, orphan, zombie)
+
+	report := procs.GetZombieReport()
+	expected := ZombieReport{
+		Count: 3,
+		Parents: []ZombieParent{
+			{Pid: 8347, Cmdline: "/usr/sbin/sshd -D", Zombies: []uint64{8350, 8352}},
+			{Pid: 1, Zombies: []uint64{8351}},
+		},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("%v not equal to expected %v", report, expected)
+	}
+	if aggregate := procs.GetAggregate(); aggregate.Zombies != 3 {
+		t.Errorf("%d not equal to expected %d", aggregate.Zombies, 3)
+	}
+}
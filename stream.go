@@ -0,0 +1,113 @@
+// Copyright 2015 Alexander Bulimov. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// broker fans out notifications about new history entries
+// to all subscribed streaming clients
+type broker struct {
+	sync.Mutex
+	subscribers map[chan struct{}]bool
+}
+
+func newBroker() *broker {
+	return &broker{subscribers: make(map[chan struct{}]bool)}
+}
+
+// Subscribe returns channel which receives a value after each
+// history update
+func (b *broker) Subscribe() chan struct{} {
+	// buffer of one lets notifications coalesce: a slow client
+	// which missed several updates gets only one, and then reads
+	// the latest history entry instead of a backlog of stale ones
+	ch := make(chan struct{}, 1)
+	b.Lock()
+	b.subscribers[ch] = true
+	b.Unlock()
+	return ch
+}
+
+// Unsubscribe removes channel from subscribers
+func (b *broker) Unsubscribe(ch chan struct{}) {
+	b.Lock()
+	delete(b.subscribers, ch)
+	b.Unlock()
+}
+
+// Len returns number of active subscribers
+func (b *broker) Len() int {
+	b.Lock()
+	defer b.Unlock()
+	return len(b.subscribers)
+}
+
+// Notify wakes up all subscribers without ever blocking the collector
+func (b *broker) Notify() {
+	b.Lock()
+	defer b.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// subscriber hasn't consumed previous notification yet
+		}
+	}
+}
+
+// streamHandler pushes each new snapshot of container's processes
+// to the client as Server-Sent Events, until client disconnects
+func streamHandler(res http.ResponseWriter, req *http.Request, containerID string) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		res.WriteHeader(500) // HTTP 500
+		fmt.Fprint(res, "Streaming is not supported")
+		return
+	}
+	q, err := parseQuery(req)
+	if err != nil {
+		res.WriteHeader(400) // HTTP 400
+		fmt.Fprint(res, err.Error())
+		return
+	}
+
+	ch := updates.Subscribe()
+	defer updates.Unsubscribe(ch)
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.WriteHeader(200)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ch:
+		}
+		ps, err := getSnapshot(getHistory(req, containerID, q.interval+1), containerID, q, 1)
+		if err != nil {
+			// container may appear later, or history is not full yet,
+			// so we report error and keep streaming
+			_, err = fmt.Fprintf(res, "event: error\ndata: %s\n\n",
+				strings.Replace(err.Error(), "\n", " ", -1))
+		} else {
+			var data []byte
+			if data, err = json.Marshal(ps); err == nil {
+				_, err = fmt.Fprintf(res, "data: %s\n\n", data)
+			}
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}